@@ -14,7 +14,7 @@
 //
 // • Context-driven lifecycle management, ensuring clean connection termination and resource cleanup.
 //
-// • Built-in support for message caching and TTL-based handling of message metadata.
+// • Built-in suppression of redelivered messages with a pluggable deduplication store.
 //
 // # Usage Example
 //
@@ -68,6 +68,16 @@
 //			fmt.Println(err)
 //		}
 //	}
+//
+// Alternatively, the client lifecycle can be bound to a caller provided context with Run, which blocks
+// until the context is cancelled or the client stops for any other reason:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//
+//	if err := c.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+//		fmt.Println(err)
+//	}
 package twitchws
 
 import (
@@ -80,7 +90,6 @@ import (
 	"time"
 
 	"github.com/coder/websocket"
-	"github.com/jellydator/ttlcache/v3"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -90,11 +99,14 @@ type OnEventFn func()
 // OnMessageEventFn defines a callback function to process message events, receiving metadata and payload as parameters.
 type OnMessageEventFn func(*Metadata, *Payload)
 
-// websocketMessageFn defines a function type that processes a websocket message, returning a Payload, event callback, and error.
-type websocketMessageFn func(*Client, *Metadata, []byte) (*Payload, OnMessageEventFn, error)
+// OnCloseFn defines a callback function to be executed when the server closes the connection with a close frame.
+type OnCloseFn func(CloseReason)
 
-// clientState represents the internal state of a client, capturing various states during its lifecycle and operations.
-type clientState int
+// OnDuplicateFn defines a callback function to be executed when a message that has already been received is suppressed.
+type OnDuplicateFn func(*Metadata)
+
+// websocketMessageFn defines a function type that processes a websocket message payload, returning a Payload, event callback, and error.
+type websocketMessageFn func(*Client, *Metadata, rawJSON) (*Payload, OnMessageEventFn, error)
 
 // websocketTwitch is the WebSocket endpoint URL for connecting to Twitch EventSub services.
 const websocketTwitch = "wss://eventsub.wss.twitch.tv/ws"
@@ -105,26 +117,6 @@ var (
 	ErrConnectionFailed = errors.New("failed to setup connection") // Failed to set up WebSocket connection
 )
 
-var (
-	// errWebsocketReadError represents an error that occurs during reading from a WebSocket connection.
-	errWebsocketReadError = errors.New("read error")
-
-	// errUnmarshalError represents an error indicating failure to unmarshal a message from its data structure.
-	errUnmarshalError = errors.New("failed to unmarshal message")
-
-	// errHandlingError represents an error that occurs during the handling of a message or operation.
-	errHandlingError = errors.New("handling error")
-
-	// errConnectionNotAlive indicates that the connection to the server is no longer active or has been lost.
-	errConnectionNotAlive = errors.New("connection is lost")
-
-	// errNotSupportedEvent represents an error indicating that an attempted operation involves an unsupported event type.
-	errNotSupportedEvent = errors.New("unsupported event")
-
-	// errReconnectTimeoutExpire represents an error indicating that the reconnection process exceeded the allowed timeout.
-	errReconnectTimeoutExpire = errors.New("reconnect awaiting timeout")
-)
-
 var (
 	messageHandlers = map[string]websocketMessageFn{
 		"session_welcome":   welcomeMessageHandler,
@@ -133,29 +125,13 @@ var (
 		"revocation":        revocationMessageHandler,
 		"session_reconnect": reconnectMessageHandler,
 	}
-	log = slog.Default()
 )
 
-const (
-
-	// stateInactive represents the client state where it is not active or engaged in any connection-related process.
-	stateInactive = iota
-
-	// stateConnecting represents the client state during the process of establishing a connection.
-	stateConnecting
+// defaultDedupWindow defines the default duration message IDs are tracked for in order to suppress redelivered messages.
+const defaultDedupWindow = 10 * time.Minute
 
-	// stateConnected indicates that the client has successfully established a connection and is in a stable connected state.
-	stateConnected
-
-	// stateReconnecting indicates that the client is attempting to reconnect after being disconnected.
-	stateReconnecting
-
-	// stateDisconnected represents the state where the client has been disconnected and requires cleanup or reconnection setup.
-	stateDisconnected
-)
-
-// defaultTTLTimeoutSec defines the default time-to-live duration in seconds for cached messages in the client's tracking system.
-const defaultTTLTimeoutSec = 10
+// defaultReconnectWelcomeTimeout defines the default duration to wait for the welcome message on the reconnect connection.
+const defaultReconnectWelcomeTimeout = time.Minute
 
 type Metadata struct {
 	MessageID           string `json:"message_id"`
@@ -207,9 +183,45 @@ type EventsubCondition struct {
 	CampaignID            string `json:"campaign_id,omitempty"`
 }
 
+// Notification is a notification or revocation message payload. Event holds a pointer to the event struct
+// registered for the subscription type and version, or the raw event JSON as json.RawMessage if lazy decoding
+// is enabled with WithLazyDecoding, see Decode.
 type Notification struct {
 	Subscription EventsubSubscription `json:"subscription"`
 	Event        interface{}          `json:"event"`
+
+	// lazy decodes the raw event on the first Decode call, nil if the event is not lazily decoded.
+	lazy *lazyEvent
+}
+
+// envelope is a message with its payload kept raw, so the payload is decoded only once by the message type handler.
+type envelope struct {
+	Metadata Metadata `json:"metadata"`
+	Payload  rawJSON  `json:"payload"`
+}
+
+// rawNotification is a notification or revocation payload with the event kept raw.
+type rawNotification struct {
+	Subscription EventsubSubscription `json:"subscription"`
+	Event        rawJSON              `json:"event"`
+}
+
+// rawJSON is a raw JSON value like json.RawMessage that references the unmarshalled data instead of copying it.
+// It is only used for messages read from the connection, which are never modified after being read.
+type rawJSON []byte
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *rawJSON) UnmarshalJSON(data []byte) error {
+	*r = data
+
+	return nil
+}
+
+// RawNotification is a notification of a subscription type or version the client has no event struct for.
+// Event holds the event JSON as received.
+type RawNotification struct {
+	Subscription EventsubSubscription `json:"subscription"`
+	Event        json.RawMessage      `json:"event"`
 }
 
 type Client struct {
@@ -246,6 +258,51 @@ type Client struct {
 	// reconnectGroupCtx provides the context for managing the lifecycle of the reconnection goroutine(s) within the client.
 	reconnectGroupCtx context.Context
 
+	// logger is used to report the client activity with structured attributes.
+	logger *slog.Logger
+
+	// metrics receives measurements of the client activity.
+	metrics Metrics
+
+	// dispatcherConfig enables asynchronous dispatch of message callbacks if set.
+	dispatcherConfig *DispatcherConfig
+
+	// dispatcher runs message callbacks asynchronously while the client is active, nil for inline dispatch.
+	dispatcher *dispatcher
+
+	// registry holds the event structs looked up before DefaultRegistry, nil if not set.
+	registry *Registry
+
+	// lazyDecoding keeps notification events raw until Notification.Decode is called.
+	lazyDecoding bool
+
+	// recorder receives every frame read from the connection, nil if not set.
+	recorder FrameRecorder
+
+	// generation counts the connections dialed by the client.
+	generation atomic.Uint64
+
+	// connGeneration is the generation of the current connection.
+	connGeneration uint64
+
+	// reconnectGeneration is the generation of the connection dialed on the server reconnect request.
+	reconnectGeneration uint64
+
+	// replaying indicates that the client handles recorded frames, so it never dials the reconnect URL.
+	replaying bool
+
+	// middlewares wrap every message callback, the first middleware being the outermost one.
+	middlewares []Middleware
+
+	// recovery indicates that panicking callbacks are recovered and reported to the onError callback.
+	recovery bool
+
+	// streamState holds the channels notifications and state transitions are delivered to, nil if disabled.
+	streamState *eventStreamState
+
+	// session stores the current session received with the latest session welcome or reconnect message.
+	session atomic.Pointer[Session]
+
 	// isActive is an atomic.Boolean that indicates whether the client is currently active or in use.
 	isActive atomic.Bool
 
@@ -258,20 +315,41 @@ type Client struct {
 	// isReconnectRequired indicates whether the client is required to reconnect, controlled via atomic operations.
 	isReconnectRequired atomic.Bool
 
-	// msgTracking maintains a cache for tracking message IDs along with their timestamps to handle deduplication and expiration.
-	msgTracking *ttlcache.Cache[string, string]
+	// msgTracking tracks received message IDs to suppress messages redelivered by Twitch.
+	msgTracking DedupStore
+
+	// dedupWindow specifies how long received message IDs are tracked to suppress redelivered messages.
+	dedupWindow time.Duration
+
+	// duplicatesSuppressed counts the messages dropped because their message ID has already been received.
+	duplicatesSuppressed atomic.Uint64
 
 	// state represents the current lifecycle state of the Client, determining its operational mode and transitions.
-	state clientState
+	state atomic.Int32
 
-	// url specifies the WebSocket server address the client connects to or interacts with.
+	// url specifies the WebSocket server address the client was created with. Every dial starts a new session there,
+	// reconnect URLs are used only to hand the current session over.
 	url string
 
 	// keepaliveTimeout represents the duration within which a keepalive message is expected to maintain connection health.
-	keepaliveTimeout time.Duration
+	// It is updated by welcome messages, which are also handled by the reconnect goroutine.
+	keepaliveTimeout atomic.Int64
+
+	// lastHeardTimestamp stores the last known timestamp in Unix nanoseconds when the client received a message or
+	// activity, zero if there is none.
+	lastHeardTimestamp atomic.Int64
 
-	// lastHeardTimestamp stores the last known timestamp when the client received a message or activity.
-	lastHeardTimestamp time.Time
+	// reconnectPolicy decides whether and when the client reconnects after a failed dial or a dropped session.
+	reconnectPolicy ReconnectPolicy
+
+	// reconnectWelcomeTimeout limits the time to wait for the welcome message on the reconnect connection.
+	reconnectWelcomeTimeout time.Duration
+
+	// reconnectAttempt counts consecutive reconnect attempts since the last received welcome message.
+	reconnectAttempt int
+
+	// reconnectStart stores the time of the first reconnect attempt in the current series.
+	reconnectStart time.Time
 
 	// onConnect is a callback executed when the client successfully connects to the WebSocket server.
 	onConnect OnEventFn
@@ -279,6 +357,18 @@ type Client struct {
 	// onDisconnect is the callback function executed when the client disconnects from the server.
 	onDisconnect OnEventFn
 
+	// onStateChange is the callback function executed on every connection state transition.
+	onStateChange OnStateChangeFn
+
+	// onClose is the callback function executed when the server closes the connection with a close frame.
+	onClose OnCloseFn
+
+	// onDuplicate is the callback function executed when a redelivered message is suppressed.
+	onDuplicate OnDuplicateFn
+
+	// onError is the callback function executed when a received message cannot be handled.
+	onError OnErrorFn
+
 	// onWelcomeMessage is a callback function to handle events triggered on receiving a welcome message from the server.
 	onWelcomeMessage OnMessageEventFn
 
@@ -288,6 +378,12 @@ type Client struct {
 	// onNotificationMessage defines a callback for handling incoming notification messages with associated metadata and payload.
 	onNotificationMessage OnMessageEventFn
 
+	// onRawNotificationMessage defines a callback for handling notifications of unsupported subscription types or versions.
+	onRawNotificationMessage OnMessageEventFn
+
+	// handlers keeps the typed notification handlers registered with On.
+	handlers typedHandlers
+
 	// onRevocationMessage is a callback function triggered when a revocation message is received by the client.
 	onRevocationMessage OnMessageEventFn
 
@@ -308,36 +404,63 @@ func NewClient(url string, opts ...Option) *Client {
 // newClient creates and initializes a new Client instance with the specified URL and optional configuration options.
 func newClient(url string, opts ...Option) *Client {
 	c := &Client{
-		conn:             nil,
-		keepaliveTimeout: time.Minute,
-		workerStop:       make(chan struct{}, 1),
-		url:              url,
-		msgTracking: ttlcache.New[string, string](
-			ttlcache.WithTTL[string, string](time.Second*defaultTTLTimeoutSec),
-			ttlcache.WithDisableTouchOnHit[string, string](),
-		),
+		conn:                    nil,
+		workerStop:              make(chan struct{}, 1),
+		url:                     url,
+		dedupWindow:             defaultDedupWindow,
+		reconnectWelcomeTimeout: defaultReconnectWelcomeTimeout,
+		logger:                  slog.Default(),
+		metrics:                 NoopMetrics{},
 	}
+	c.keepaliveTimeout.Store(int64(time.Minute))
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	if c.msgTracking == nil {
+		c.msgTracking = NewMemoryDedupStore()
+	}
+
+	c.wrapCallbacks()
+	c.openEventStream()
+
 	return c
 }
 
 // Connect establishes a connection by initializing contexts, transitioning to the connecting state, and starting the worker.
 // Returns an error if the client is already active.
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext behaves like Connect, but derives the client lifecycle from the provided context.
+// Cancelling ctx stops the worker the same way Close does; the terminal error is then reported by Wait.
+func (c *Client) ConnectContext(ctx context.Context) error {
 	if !c.setActive() {
 		return ErrAlreadyInUse
 	}
 
-	c.state = stateConnecting
-	c.initMainContext()
+	c.openEventStream()
+	c.setState(StateConnecting, nil)
+	c.initMainContext(ctx)
 	c.initOperationContext()
 	c.waitGroup, c.waitGroupCtx = errgroup.WithContext(c.operationContext())
+
+	if c.dispatcherConfig != nil {
+		c.dispatcher = newDispatcher(*c.dispatcherConfig, c.metrics)
+	}
+
 	c.waitGroup.Go(func() error {
-		return worker(c)
+		err := worker(c)
+
+		if c.dispatcher != nil {
+			c.dispatcher.stop()
+		}
+
+		c.closeEventStream()
+
+		return err
 	})
 
 	return nil
@@ -348,6 +471,26 @@ func (c *Client) Wait() error {
 	return c.waitGroup.Wait()
 }
 
+// Run connects the client and blocks until it stops, either because ctx is cancelled, Close is called
+// or the connection cannot be maintained. Returns ctx.Err() if the client was stopped by the context,
+// otherwise the error that terminated the worker.
+func (c *Client) Run(ctx context.Context) error {
+	if err := c.ConnectContext(ctx); err != nil {
+		return err
+	}
+
+	err := c.Wait()
+	// the worker has already exited here, so only release the client for further use
+	c.setInactive()
+	c.ctxCancel()
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
 // Close gracefully terminates the client's connection, stops the worker, cancels contexts, and waits for cleanup to complete.
 func (c *Client) Close() error {
 	if !c.setInactive() {
@@ -394,12 +537,33 @@ func (c *Client) getIsWelcomeReceived() bool {
 
 // isConnectionAlive checks if the connection is still alive by comparing the current time with the last heard timestamp.
 func (c *Client) isConnectionAlive() bool {
-	return time.Now().Before(c.lastHeardTimestamp.Add(c.keepaliveTimeout))
+	return time.Now().Before(time.Unix(0, c.lastHeardTimestamp.Load()).Add(c.getKeepaliveTimeout()))
 }
 
-// initMainContext initializes the main context and its cancellation function for the Client.
-func (c *Client) initMainContext() {
-	c.ctx, c.ctxCancel = context.WithCancel(context.Background())
+// getKeepaliveTimeout returns the duration within which a message is expected to keep the connection alive.
+func (c *Client) getKeepaliveTimeout() time.Duration {
+	return time.Duration(c.keepaliveTimeout.Load())
+}
+
+// updateLastHeardTimestamp validates the message timestamp and stores the local receipt time as the last heard
+// timestamp, so the server clock skew and out-of-order timestamps do not affect the keepalive check.
+// Returns the parsed message timestamp.
+func (c *Client) updateLastHeardTimestamp(timestamp string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+
+	if err != nil {
+		c.lastHeardTimestamp.Store(0)
+		return t, err
+	}
+
+	c.lastHeardTimestamp.Store(time.Now().UnixNano())
+
+	return t, nil
+}
+
+// initMainContext initializes the main context and its cancellation function for the Client from the parent context.
+func (c *Client) initMainContext(parent context.Context) {
+	c.ctx, c.ctxCancel = context.WithCancel(parent)
 }
 
 // initOperationContext initializes the operation context and its cancellation function using the main context.
@@ -417,27 +581,62 @@ func (c *Client) operationContext() context.Context {
 	return c.opCtx
 }
 
-// cleanUp resets client state, clears message tracking, and closes the connection with appropriate status and reason.
+// cleanUp resets client state and closes the connection with appropriate status and reason.
 func (c *Client) cleanUp(err error) {
-	c.lastHeardTimestamp = time.Time{}
+	c.lastHeardTimestamp.Store(0)
 	c.isWelcomeReceived.Store(false)
-	c.msgTracking.DeleteAll()
 
 	if !c.getIsConnected() {
 		return
 	}
 
-	log.Error("Clean up error", "err", err)
-
 	status := websocket.StatusNormalClosure
 	reason := ""
 
-	if err != nil {
+	// an error caused by the client shutdown does not make the closure abnormal
+	if err != nil && c.mainContext().Err() == nil {
+		c.sessionLogger().Warn("Closing connection after error", "err", err)
 		status = websocket.StatusInternalError
 		reason = fmt.Sprintf("error occurred: %s", err)
+	} else {
+		c.sessionLogger().Debug("Closing connection")
 	}
 
 	_ = c.conn.Close(status, reason)
+	c.session.Store(nil)
+}
+
+// Session returns the current session and true if a session has been established, false otherwise.
+// The session is updated on every session welcome and reconnect message and is safe to read concurrently.
+func (c *Client) Session() (Session, bool) {
+	if s := c.session.Load(); s != nil {
+		return *s, true
+	}
+
+	return Session{}, false
+}
+
+// currentSessionID returns the ID of the current session or an empty string if there is no session.
+func (c *Client) currentSessionID() string {
+	s, _ := c.Session()
+
+	return s.ID
+}
+
+// sessionLogger returns the client logger annotated with the current session ID.
+func (c *Client) sessionLogger() *slog.Logger {
+	return c.logger.With("session_id", c.currentSessionID())
+}
+
+// messageLogger returns the client logger annotated with the current session ID and the message attributes.
+func (c *Client) messageLogger(m *Metadata) *slog.Logger {
+	l := c.sessionLogger().With("message_id", m.MessageID, "message_type", m.MessageType)
+
+	if m.SubscriptionType != "" {
+		l = l.With("subscription_type", m.SubscriptionType)
+	}
+
+	return l
 }
 
 // worker manages the state transitions of the Client, handling connection, reconnection, and disconnection processes.
@@ -449,17 +648,18 @@ func worker(c *Client) error {
 	)
 
 	for {
-		switch c.state {
-		case stateConnecting:
+		switch c.State() {
+		case StateConnecting:
 			err = connectingStateHandler(c)
 
 			if err == nil {
-				c.state = stateConnected
+				c.setState(StateConnected, nil)
 			} else {
-				shouldExit = true
-				c.state = stateDisconnected
+				// without a reconnect policy a failed dial is terminal
+				shouldExit = c.reconnectPolicy == nil
+				c.setState(StateDisconnected, err)
 			}
-		case stateConnected:
+		case StateConnected:
 			c.setConnected()
 
 			if c.onConnect != nil {
@@ -467,26 +667,29 @@ func worker(c *Client) error {
 			}
 
 			shouldExit, err = connectedStateHandler(c)
-			if c.isReconnectRequired.CompareAndSwap(true, false) {
-				c.state = stateReconnecting
 
-				if err != nil {
-					c.state = stateDisconnected
-				}
+			if c.getIsWelcomeReceived() {
+				c.resetReconnectAttempts()
+			}
+
+			if c.isReconnectRequired.CompareAndSwap(true, false) && err == nil {
+				c.setState(StateReconnecting, nil)
 			} else {
-				c.state = stateDisconnected
+				c.setState(StateDisconnected, err)
 			}
-		case stateReconnecting:
+		case StateReconnecting:
 			c.initOperationContext()
 			err := c.conn.Close(websocket.StatusNormalClosure, "")
 
 			if err != nil {
-				log.Error("reconnecting state", "err", err)
+				c.sessionLogger().Warn("Failed to close connection after reconnect", "err", err)
 			}
 
 			c.conn, c.reconnectConn = c.reconnectConn, nil
-			c.state = stateConnected
-		case stateDisconnected:
+			c.connGeneration = c.reconnectGeneration
+			c.metrics.Reconnect(ReconnectReasonSessionReconnect)
+			c.setState(StateConnected, nil)
+		case StateDisconnected:
 			c.cleanUp(err)
 			c.setDisconnected()
 
@@ -495,18 +698,63 @@ func worker(c *Client) error {
 			}
 
 			if !shouldExit {
-				c.state = stateConnecting
+				shouldExit, err = c.awaitReconnect(err)
+			}
+
+			if !shouldExit {
+				c.metrics.Reconnect(ReconnectReasonConnectionLost)
+				c.setState(StateConnecting, nil)
 			} else {
-				c.state = stateInactive
+				c.setState(StateInactive, err)
 			}
-		case stateInactive:
+		case StateInactive:
 			return err
 		default:
-			log.Error("unsupported state", "state", c.state)
+			c.logger.Error("Unsupported state", "state", c.State())
 		}
 	}
 }
 
+// resetReconnectAttempts resets the reconnect attempts series once a session has been established.
+func (c *Client) resetReconnectAttempts() {
+	c.reconnectAttempt = 0
+	c.reconnectStart = time.Time{}
+}
+
+// awaitReconnect consults the reconnect policy and waits for the requested delay before the next connection attempt.
+// Returns true if the worker should exit instead, along with the error to report: the last error if the policy gave up
+// or nil if the client was stopped while waiting.
+func (c *Client) awaitReconnect(err error) (bool, error) {
+	if c.reconnectPolicy == nil {
+		return false, err
+	}
+
+	if c.reconnectAttempt == 0 {
+		c.reconnectStart = time.Now()
+	}
+
+	c.reconnectAttempt++
+	delay, ok := c.reconnectPolicy.NextDelay(c.reconnectAttempt, time.Since(c.reconnectStart))
+
+	if !ok {
+		c.logger.Error("Reconnect policy gave up", "attempts", c.reconnectAttempt-1, "err", err)
+		return true, err
+	}
+
+	c.logger.Info("Reconnect scheduled", "attempt", c.reconnectAttempt, "delay", delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return false, err
+	case <-c.workerStop:
+		return true, nil
+	case <-c.mainContext().Done():
+		return true, nil
+	}
+}
+
 // connectingStateHandler attempts to establish a WebSocket connection for the provided client.
 // Returns an error if the connection fails, appending ErrConnectionFailed to the error chain.
 func connectingStateHandler(c *Client) error {
@@ -518,6 +766,8 @@ func connectingStateHandler(c *Client) error {
 		return err
 	}
 
+	c.connGeneration = c.generation.Add(1)
+
 	return nil
 }
 
@@ -533,103 +783,202 @@ func connectedStateHandler(c *Client) (bool, error) {
 			err := singleMessageHandler(c)
 
 			if err != nil {
-				log.Warn("Message Handling Error", "err", err)
 				isAwaitingReconnect := c.isReconnectRequired.Load()
 
-				if errors.Is(err, context.Canceled) || isAwaitingReconnect {
+				if errors.Is(err, context.Canceled) || c.mainContext().Err() != nil || isAwaitingReconnect {
 					if isAwaitingReconnect {
 						err := c.reconnectGroup.Wait()
-						log.Debug("Reconnect done", "err", err)
+						c.sessionLogger().Debug("Reconnect done", "err", err)
 
 						return false, err
 					}
 
+					c.sessionLogger().Debug("Connection stopped", "err", err)
+
 					return true, err
-				} else if !errors.Is(err, errNotSupportedEvent) {
-					log.Error("Error while connected", "err", err)
+				}
+
+				if recovery, closed := c.handleClose(err); closed {
+					return recovery == recoveryStop, err
+				}
+
+				if errors.Is(err, ErrKeepaliveTimeout) {
+					return false, c.keepaliveExpired()
+				}
+
+				if !errors.Is(err, ErrUnsupportedEvent) {
+					c.sessionLogger().Warn("Connection error", "err", err)
 					return false, err
 				}
 			}
 		}
 
-		c.msgTracking.DeleteExpired()
 		if c.getIsWelcomeReceived() && !c.isConnectionAlive() {
-			log.Debug("no keepalive/event messages - reconnect")
-			return false, errConnectionNotAlive
+			return false, c.keepaliveExpired()
 		}
 	}
 }
 
+// keepaliveExpired reports that no keepalive or event message has been received in time.
+// Returns ErrKeepaliveTimeout.
+func (c *Client) keepaliveExpired() error {
+	c.sessionLogger().Warn("No keepalive or event messages received in time")
+	c.metrics.KeepaliveTimeout()
+
+	return ErrKeepaliveTimeout
+}
+
 // singleMessageHandler processes a single incoming WebSocket message, updates message tracking, and invokes appropriate handlers.
 // Returns an error if message reading, metadata extraction, or handling fails.
 func singleMessageHandler(c *Client) error {
-	ctx, cancel := context.WithTimeout(c.operationContext(), c.keepaliveTimeout)
+	ctx, cancel := context.WithTimeout(c.operationContext(), c.getKeepaliveTimeout())
 	defer cancel()
 
 	msgType, data, err := c.conn.Read(ctx)
 
 	if err != nil {
-		return errors.Join(err, errWebsocketReadError)
+		// the read deadline is the keepalive timeout, so its expiry means the server went silent; the websocket
+		// library may report it as a closed connection, hence the read context is checked instead of the error
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && c.operationContext().Err() == nil {
+			return ErrKeepaliveTimeout
+		}
+
+		return errors.Join(err, ErrRead)
 	}
 
-	log.Debug("Read message", "msgType", msgType, "data", data, "err", err)
-	m, err := getMessageMetadata(msgType, data)
+	c.recordFrame(c.connGeneration, msgType, data)
+
+	return c.handleMessage(ctx, msgType, data)
+}
+
+// handleMessage decodes a message read from the connection, suppresses duplicates and invokes the appropriate
+// handlers. Returns an error if the message cannot be decoded or handled.
+func (c *Client) handleMessage(ctx context.Context, msgType websocket.MessageType, data []byte) error {
+	env, err := decodeEnvelope(msgType, data)
 
 	if err != nil {
+		c.sessionLogger().Warn("Invalid message received", "err", err)
+
+		if errors.Is(err, ErrDecode) {
+			c.metrics.DecodeFailed("", "")
+			err = &DecodeError{Raw: data, Err: err}
+		}
+
+		c.reportError(err, nil, data)
+
 		return err
 	}
 
-	item := c.msgTracking.Get(m.MessageID)
+	m := &env.Metadata
+	c.metrics.MessageReceived(m.MessageType, m.SubscriptionType)
 
-	if item != nil {
-		log.Debug("Message ID already present", "msgID", item.Key())
+	if c.logger.Enabled(ctx, slog.LevelDebug) {
+		c.messageLogger(m).Debug("Message received")
 	}
 
-	c.msgTracking.Set(m.MessageID, m.MessageTimestamp, time.Second*c.keepaliveTimeout)
+	if c.isDuplicate(m) {
+		c.messageLogger(m).Debug("Duplicate message suppressed")
+		c.duplicatesSuppressed.Add(1)
+		c.metrics.DuplicateSuppressed(m.MessageType)
+
+		if c.onDuplicate != nil {
+			c.onDuplicate(m)
+		}
+
+		return nil
+	}
 
 	if h, ok := messageHandlers[m.MessageType]; ok {
 		var (
 			onEvent OnMessageEventFn
 			p       *Payload
 		)
-		p, onEvent, err = h(c, m, data)
+		p, onEvent, err = h(c, m, env.Payload)
 
 		if err != nil {
-			return errors.Join(err, errHandlingError)
+			if errors.Is(err, ErrUnsupportedEvent) {
+				c.messageLogger(m).Warn("Unsupported event", "version", m.SubscriptionVersion, "err", err)
+				c.metrics.UnsupportedEvent(m.SubscriptionType, m.SubscriptionVersion)
+			} else {
+				c.metrics.DecodeFailed(m.MessageType, m.SubscriptionType)
+			}
+
+			err = &DecodeError{
+				MessageType:      m.MessageType,
+				SubscriptionType: m.SubscriptionType,
+				Version:          m.SubscriptionVersion,
+				Raw:              data,
+				Err:              err,
+			}
+			c.reportError(err, m, data)
+
+			return errors.Join(err, ErrHandling)
 		}
 
 		if onEvent != nil {
-			onEvent(m, p)
+			c.dispatch(onEvent, m, p)
 		}
+
+		c.publishEvent(m, p)
 	} else {
-		log.Warn("unknown Twitch message type", "msgType", m.MessageType)
+		c.messageLogger(m).Warn("Unknown Twitch message type")
+		c.reportError(ErrUnknownMessageType, m, data)
 	}
 
 	return nil
 }
 
-// getMessageMetadata extracts and unmarshals the metadata from a WebSocket message, returning it or an appropriate error.
-func getMessageMetadata(msgType websocket.MessageType, data []byte) (*Metadata, error) {
+// dispatch invokes the message callback either inline or through the dispatcher, if configured.
+func (c *Client) dispatch(fn OnMessageEventFn, m *Metadata, p *Payload) {
+	if c.dispatcher == nil {
+		fn(m, p)
+		return
+	}
+
+	c.dispatcher.dispatch(c.mainContext(), dispatchItem{fn: fn, m: m, p: p})
+}
+
+// isDuplicate checks whether a message with the same ID has already been received within the deduplication window.
+// Messages seen for the first time are recorded so that their redelivery is recognized later.
+func (c *Client) isDuplicate(m *Metadata) bool {
+	if m.MessageID == "" {
+		return false
+	}
+
+	return c.msgTracking.SeenOrMark(m.MessageID, c.dedupWindow)
+}
+
+// DuplicatesSuppressed returns the number of redelivered messages dropped by the client.
+func (c *Client) DuplicatesSuppressed() uint64 {
+	return c.duplicatesSuppressed.Load()
+}
+
+// decodeEnvelope unmarshals the metadata of a WebSocket message keeping its payload raw, returning it or
+// an appropriate error.
+func decodeEnvelope(msgType websocket.MessageType, data []byte) (*envelope, error) {
 	if msgType == websocket.MessageBinary {
-		return nil, errWebsocketReadError
+		return nil, ErrBinaryMessage
 	}
 
-	m, err := unmarshalMetadata(data)
+	var env envelope
 
-	if err != nil {
-		return nil, errors.Join(err, errUnmarshalError)
+	if err := unmarshalEnvelope(data, &env); err != nil {
+		return nil, err
 	}
 
-	return m, nil
+	return &env, nil
 }
 
 // reconnectNewConnection attempts to reconnect the client to a new WebSocket connection using the specified URL.
-// It updates the client's URL and establishes a new connection with the server, returning an error if it fails.
+// It establishes a new connection with the server, returning an error if it fails.
 func reconnectNewConnection(c *Client, url string) error {
 	var err error
-	c.url = url
 	c.reconnectConn, _, err = websocket.Dial(c.mainContext(), url, nil)
 
+	if err == nil {
+		c.reconnectGeneration = c.generation.Add(1)
+	}
+
 	return err
 }
 
@@ -641,33 +990,40 @@ func reconnectWaitWelcome(c *Client) error {
 		err             error
 		welcomeReceived bool
 	)
-	end := time.Now().Add(time.Minute)
-	log.Debug("start reconnect welcome message wait")
+	end := time.Now().Add(c.reconnectWelcomeTimeout)
+	c.sessionLogger().Debug("Waiting for reconnect welcome message")
 
 	for {
 		if !end.After(time.Now()) {
-			return errReconnectTimeoutExpire
+			return ErrReconnectTimeout
 		}
 
 		err = func() error {
-			rCtx, rCancel := context.WithTimeout(c.mainContext(), time.Minute)
+			rCtx, rCancel := context.WithDeadline(c.mainContext(), end)
 			defer rCancel()
 
 			msgType, data, err := c.reconnectConn.Read(rCtx)
 
+			if err != nil && rCtx.Err() != nil && c.mainContext().Err() == nil {
+				return ErrReconnectTimeout
+			}
+
 			if err != nil {
+				c.handleClose(err)
 				return err
 			}
 
-			m, err := getMessageMetadata(msgType, data)
+			c.recordFrame(c.reconnectGeneration, msgType, data)
+
+			env, err := decodeEnvelope(msgType, data)
 
 			if err != nil {
 				return err
 			}
 
-			if m.MessageType == "session_welcome" {
-				log.Debug("received reconnect wait welcome message")
-				_, _, err = welcomeMessageHandler(c, m, data)
+			if m := &env.Metadata; m.MessageType == "session_welcome" {
+				c.messageLogger(m).Debug("Reconnect welcome message received")
+				_, _, err = welcomeMessageHandler(c, m, env.Payload)
 
 				if err != nil {
 					return err
@@ -684,7 +1040,7 @@ func reconnectWaitWelcome(c *Client) error {
 		}
 
 		if welcomeReceived {
-			log.Debug("Reconnect cancel operation context")
+			c.sessionLogger().Debug("Reconnect cancel operation context")
 			// cancel operation context to allow connections swap
 			c.opCtxCancel()
 			break
@@ -695,174 +1051,195 @@ func reconnectWaitWelcome(c *Client) error {
 }
 
 // reconnectHandler attempts to reconnect the client to a new connection and waits for a "session_welcome" message.
-// Returns an error if reconnecting or receiving the welcome message fails.
+// The reconnect URL is dialed only for the handover, later connections start a new session at the client URL.
+// Returns a *ReconnectError if reconnecting or receiving the welcome message fails.
 func reconnectHandler(c *Client, url string) error {
 	err := reconnectNewConnection(c, url)
-	log.Debug("Reconnect new connection", "url", url, "err", err)
-
 	if err != nil {
-		return err
+		c.sessionLogger().Warn("Reconnect dial failed", "err", err)
+		return &ReconnectError{URL: url, Err: err}
 	}
 
-	return reconnectWaitWelcome(c)
+	c.sessionLogger().Debug("Reconnect connection established")
+
+	if err = reconnectWaitWelcome(c); err != nil {
+		_ = c.reconnectConn.CloseNow()
+		return &ReconnectError{URL: url, Err: err}
+	}
+
+	return nil
 }
 
 // welcomeMessageHandler processes the "session_welcome" message, updates client state, and returns payload and callback.
-func welcomeMessageHandler(c *Client, metadata *Metadata, data []byte) (*Payload, OnMessageEventFn, error) {
-	s, err := unmarshalSession(data)
+func welcomeMessageHandler(c *Client, metadata *Metadata, payload rawJSON) (*Payload, OnMessageEventFn, error) {
+	s, err := unmarshalSession(payload)
 	e := Payload{
 		Payload: s,
 	}
 
 	if err == nil {
-		c.keepaliveTimeout = keepaliveIntervalCalc(s.KeepaliveTimeoutSeconds)
+		c.keepaliveTimeout.Store(int64(keepaliveIntervalCalc(s.KeepaliveTimeoutSeconds)))
+		c.session.Store(&s)
 		c.isWelcomeReceived.Store(true)
-		c.lastHeardTimestamp, err = time.Parse(time.RFC3339Nano, metadata.MessageTimestamp)
+		_, err = c.updateLastHeardTimestamp(metadata.MessageTimestamp)
 	}
 
 	return &e, c.onWelcomeMessage, err
 }
 
 // keepaliveMessageHandler processes "session_keepalive" messages, updates last heard timestamp, and returns payload and callback.
-func keepaliveMessageHandler(c *Client, metadata *Metadata, data []byte) (*Payload, OnMessageEventFn, error) {
+// The keepalive payload is always empty, so it is not decoded.
+func keepaliveMessageHandler(c *Client, metadata *Metadata, _ rawJSON) (*Payload, OnMessageEventFn, error) {
 	e := Payload{
 		Payload: struct{}{},
 	}
-	err := unmarshalEnvelope(data, &e)
-
-	if err == nil {
-		c.lastHeardTimestamp, err = time.Parse(time.RFC3339Nano, metadata.MessageTimestamp)
-	}
+	_, err := c.updateLastHeardTimestamp(metadata.MessageTimestamp)
 
 	return &e, c.onKeepaliveMessage, err
 }
 
 // notificationMessageHandler processes "notification" messages by parsing data into a payload and updating client state.
-// It returns the parsed payload, the onNotificationMessage callback function, and any error encountered during processing.
-func notificationMessageHandler(c *Client, metadata *Metadata, data []byte) (*Payload, OnMessageEventFn, error) {
-	payload, err := processNotification(data)
+// It returns the parsed payload, the callback invoking onNotificationMessage along with the typed handlers,
+// and any error encountered during processing. Notifications of unsupported subscription types or versions are passed
+// to onRawNotificationMessage as RawNotification, if set. The event is decoded only if there is anything to consume it.
+func notificationMessageHandler(c *Client, metadata *Metadata, payload rawJSON) (*Payload, OnMessageEventFn, error) {
+	raw, err := decodeRawNotification(payload)
+	e := Payload{}
+	onEvent := c.handleNotification
 
 	if err == nil {
-		c.lastHeardTimestamp, err = time.Parse(time.RFC3339Nano, metadata.MessageTimestamp)
-	}
-
-	log.Debug("notification", "payload", payload)
+		var n Notification
+		n, err = decodeNotification(raw, c.registries(), c.eventDecoding(raw.Subscription.Type))
+		e.Payload = n
 
-	return payload, c.onNotificationMessage, err
-}
+		if errors.Is(err, ErrUnsupportedEvent) && c.onRawNotificationMessage != nil {
+			rn := RawNotification{Subscription: raw.Subscription, Event: json.RawMessage(raw.Event)}
+			e.Payload, onEvent, err = rn, c.onRawNotificationMessage, nil
+		}
+	}
 
-// revocationMessageHandler processes a "revocation" message, updates the client's state, and returns payload and callback.
-func revocationMessageHandler(c *Client, metadata *Metadata, data []byte) (*Payload, OnMessageEventFn, error) {
-	payload, err := processNotification(data)
+	var sentAt time.Time
 
 	if err == nil {
-		c.lastHeardTimestamp, err = time.Parse(time.RFC3339Nano, metadata.MessageTimestamp)
+		sentAt, err = c.updateLastHeardTimestamp(metadata.MessageTimestamp)
 	}
 
-	log.Debug("revocation", "payload", payload)
+	if err == nil {
+		c.metrics.NotificationLatency(metadata.SubscriptionType, time.Since(sentAt))
+	}
 
-	return payload, c.onRevocationMessage, err
+	return &e, onEvent, err
 }
 
-// reconnectMessageHandler processes the "session_reconnect" message, initializing reconnect tasks and callbacks.
-func reconnectMessageHandler(c *Client, _ *Metadata, data []byte) (*Payload, OnMessageEventFn, error) {
-	s, err := unmarshalSession(data)
+// revocationMessageHandler processes a "revocation" message, updates the client's state, and returns payload and callback.
+// Revocations carry no event, so the Notification.Event of the payload is nil.
+func revocationMessageHandler(c *Client, metadata *Metadata, payload rawJSON) (*Payload, OnMessageEventFn, error) {
+	raw, err := decodeRawNotification(payload)
+	n := Notification{Subscription: raw.Subscription}
 	e := Payload{
-		Payload: s,
+		Payload: n,
 	}
 
-	log.Debug("reconnect message received", "payload", s)
+	if err == nil {
+		_, err = c.updateLastHeardTimestamp(metadata.MessageTimestamp)
+	}
 
 	if err == nil {
-		c.isReconnectRequired.Store(true)
-		c.reconnectGroup, c.reconnectGroupCtx = errgroup.WithContext(c.ctx)
-		c.reconnectGroup.Go(func() error {
-			return reconnectHandler(c, s.ReconnectURL)
-		})
+		c.messageLogger(metadata).Info("Subscription revoked", "status", n.Subscription.Status)
 	}
 
-	return &e, c.onReconnectMessage, err
+	return &e, c.onRevocationMessage, err
 }
 
-// unmarshalMetadata unmarshals JSON-encoded data into a Metadata struct and returns it or an error if unmarshaling fails.
-func unmarshalMetadata(data []byte) (*Metadata, error) {
-	var m struct {
-		Metadata `json:"metadata"`
+// reconnectMessageHandler processes the "session_reconnect" message, initializing reconnect tasks and callbacks.
+func reconnectMessageHandler(c *Client, _ *Metadata, payload rawJSON) (*Payload, OnMessageEventFn, error) {
+	s, err := unmarshalSession(payload)
+	e := Payload{
+		Payload: s,
 	}
 
-	err := json.Unmarshal(data, &m)
+	c.sessionLogger().Info("Reconnect requested by server")
 
-	if err != nil {
-		return nil, err
+	if err == nil {
+		c.session.Store(&s)
+
+		// a replayed session_reconnect only updates the session
+		if !c.replaying {
+			c.isReconnectRequired.Store(true)
+			c.reconnectGroup, c.reconnectGroupCtx = errgroup.WithContext(c.ctx)
+			c.reconnectGroup.Go(func() error {
+				return reconnectHandler(c, s.ReconnectURL)
+			})
+		}
 	}
 
-	return &m.Metadata, nil
+	return &e, c.onReconnectMessage, err
 }
 
-// unmarshalEnvelope deserializes JSON data into the provided interface and logs any errors encountered during unmarshalling.
+// unmarshalEnvelope deserializes JSON data into the provided interface.
 func unmarshalEnvelope(data []byte, e any) error {
-	err := json.Unmarshal(data, &e)
-
-	if err != nil {
-		log.Error("unmarshal envelope", "err", err)
+	if err := json.Unmarshal(data, &e); err != nil {
+		return errors.Join(ErrDecode, err)
 	}
 
-	return err
+	return nil
 }
 
-// unmarshalSession extracts a Session object from a JSON byte slice, returning it or an error if deserialization fails.
-func unmarshalSession(data []byte) (Session, error) {
-	var payload struct {
-		Payload struct {
-			Session `json:"session"`
-		} `json:"payload"`
+// unmarshalSession extracts a Session object from a message payload, returning it or an error if deserialization fails.
+func unmarshalSession(payload rawJSON) (Session, error) {
+	var p struct {
+		Session `json:"session"`
 	}
 
-	if err := unmarshalEnvelope(data, &payload); err != nil {
+	if err := unmarshalEnvelope(payload, &p); err != nil {
 		return Session{}, err
 	}
 
-	return payload.Payload.Session, nil
+	return p.Session, nil
 }
 
-// unmarshalNotification parses the provided JSON data into a Notification object and validates its subscription and event details.
-func unmarshalNotification(data []byte) (Notification, error) {
-	var msg json.RawMessage
-	payload := Payload{
-		Payload: &msg,
-	}
+// decodeRawNotification parses a notification or revocation payload keeping the event raw.
+func decodeRawNotification(payload rawJSON) (rawNotification, error) {
+	var raw rawNotification
+	err := unmarshalEnvelope(payload, &raw)
 
-	if err := unmarshalEnvelope(data, &payload); err != nil {
-		return Notification{}, err
-	}
+	return raw, err
+}
 
+// decodeNotification validates the subscription type and version of the raw notification against the registries
+// and handles its event according to the decoding mode.
+func decodeNotification(raw rawNotification, rc registryChain, decoding eventDecoding) (Notification, error) {
 	notification := Notification{
-		Subscription: EventsubSubscription{},
-		Event:        &msg,
+		Subscription: raw.Subscription,
 	}
-
-	if err := unmarshalEnvelope(msg, &notification); err != nil {
-		return Notification{}, err
-	}
-
-	foundEventScope, err := getEventSub(notification.Subscription.Type, notification.Subscription.Version)
+	foundEventScope, err := rc.lookup(notification.Subscription.Type, notification.Subscription.Version)
 
 	switch {
-	case errors.Is(err, errEventSubNotFound):
-		err := fmt.Errorf("unsupported event: %s", notification.Subscription.Type)
-		return Notification{}, errors.Join(errNotSupportedEvent, errEventSubNotFound, err)
-	case errors.Is(err, errEventSubVersion):
+	case errors.Is(err, ErrUnsupportedType):
+		err := fmt.Errorf("unsupported event type: %s", notification.Subscription.Type)
+		return Notification{}, errors.Join(ErrUnsupportedEvent, ErrUnsupportedType, err)
+	case errors.Is(err, ErrUnsupportedVersion):
 		err := fmt.Errorf("unsupported event version: %s", notification.Subscription.Version)
-		return Notification{}, errors.Join(errNotSupportedEvent, errEventSubVersion, err)
+		return Notification{}, errors.Join(ErrUnsupportedEvent, ErrUnsupportedVersion, err)
 	default:
 		if err != nil {
 			err := fmt.Errorf("unexpected error: %w", err)
-			return Notification{}, errors.Join(errNotSupportedEvent, err)
+			return Notification{}, errors.Join(ErrUnsupportedEvent, err)
 		}
 	}
 
-	event := foundEventScope.MsgType
-	if err := unmarshalEnvelope(msg, event); err != nil {
+	switch decoding {
+	case eventDecodingSkip:
+		return notification, nil
+	case eventDecodingLazy:
+		notification.Event = json.RawMessage(raw.Event)
+		notification.lazy = &lazyEvent{raw: json.RawMessage(raw.Event), newEvent: foundEventScope.New}
+
+		return notification, nil
+	}
+
+	event := foundEventScope.New()
+	if err := unmarshalEnvelope(raw.Event, event); err != nil {
 		return Notification{}, err
 	}
 
@@ -878,14 +1255,3 @@ func keepaliveIntervalCalc(keepaliveInterval int) time.Duration {
 	// to avoid disconnection due to small drift in message delivery
 	return time.Duration(keepaliveInterval*100/keepalivePercent) * time.Second
 }
-
-// processNotification parses the notification data into a Payload and returns it along with any error encountered.
-func processNotification(data []byte) (*Payload, error) {
-	notification, err := unmarshalNotification(data)
-
-	payload := Payload{
-		Payload: notification,
-	}
-
-	return &payload, err
-}
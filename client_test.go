@@ -0,0 +1,1094 @@
+package twitchws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+	"github.com/vpetrigo/go-twitch-ws/pkg/twitchwstest"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := NewClient(websocketTwitch, WithLogger(logger))
+	sessionID := "AQoQexAWVYKSTIu4ec_2VAxyuhAB"
+	c.session.Store(&Session{ID: sessionID})
+
+	c.messageLogger(&Metadata{
+		MessageID:        "befa7b53-d79d-478f-86b9-120f112b044e",
+		MessageType:      "notification",
+		SubscriptionType: "channel.follow",
+	}).Info("test")
+
+	out := buf.String()
+
+	for _, attr := range []string{
+		"session_id=" + sessionID,
+		"message_id=befa7b53-d79d-478f-86b9-120f112b044e",
+		"message_type=notification",
+		"subscription_type=channel.follow",
+	} {
+		if !strings.Contains(out, attr) {
+			t.Errorf("expected %q in log record: %s", attr, out)
+		}
+	}
+}
+
+func TestWithLoggerNil(t *testing.T) {
+	c := NewClient(websocketTwitch, WithLogger(nil))
+
+	if c.logger == nil {
+		t.Fatal("expected default logger to be used")
+	}
+}
+
+func TestClientSession(t *testing.T) {
+	c := NewClient(websocketTwitch)
+
+	if _, ok := c.Session(); ok {
+		t.Fatal("expected no session before welcome message")
+	}
+
+	data := []byte(`{
+		"metadata": {
+			"message_id": "96a3f3b5-5dec-4eed-908e-e11ee657416c",
+			"message_type": "session_welcome",
+			"message_timestamp": "2023-07-19T14:56:51.634234626Z"
+		},
+		"payload": {
+			"session": {
+				"id": "AQoQILE98gtqShGmLD7AM6yJThAB",
+				"status": "connected",
+				"connected_at": "2023-07-19T14:56:51.616329898Z",
+				"keepalive_timeout_seconds": 10,
+				"reconnect_url": null
+			}
+		}
+	}`)
+	env, err := decodeEnvelope(websocket.MessageText, data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = welcomeMessageHandler(c, &env.Metadata, env.Payload); err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := c.Session()
+	expected := Session{
+		ID:                      "AQoQILE98gtqShGmLD7AM6yJThAB",
+		Status:                  "connected",
+		ConnectedAt:             "2023-07-19T14:56:51.616329898Z",
+		KeepaliveTimeoutSeconds: 10,
+	}
+
+	if !ok || s != expected {
+		t.Errorf("expected session %+v, got %+v (%v)", expected, s, ok)
+	}
+}
+
+func TestUnmarshalNotificationFreshEvent(t *testing.T) {
+	first, err := unmarshalTestNotification(chatMessageNotification(0), registryChain{DefaultRegistry})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := unmarshalTestNotification(chatMessageNotification(1), registryChain{DefaultRegistry})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstEvent := first.Event.(*eventsub.ChannelChatMessage)
+	secondEvent := second.Event.(*eventsub.ChannelChatMessage)
+
+	if firstEvent == secondEvent {
+		t.Fatal("expected every notification to get its own event instance")
+	}
+
+	if firstEvent.Message.Text != "message 0" || secondEvent.Message.Text != "message 1" {
+		t.Errorf("unexpected event messages: %q, %q", firstEvent.Message.Text, secondEvent.Message.Text)
+	}
+}
+
+func TestClientConcurrentNotifications(t *testing.T) {
+	const (
+		clients       = 2
+		notifications = 100
+	)
+
+	url := newTestServer(t, notifications)
+
+	var wg sync.WaitGroup
+
+	for range clients {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var events []*eventsub.ChannelChatMessage
+
+			c := NewClient(url, WithLogger(discardLogger()), WithOnNotification(func(_ *Metadata, p *Payload) {
+				events = append(events, p.Payload.(Notification).Event.(*eventsub.ChannelChatMessage))
+
+				if len(events) == notifications {
+					cancel()
+				}
+			}))
+			_ = c.Run(ctx)
+
+			if len(events) != notifications {
+				t.Errorf("expected %d notifications, got %d", notifications, len(events))
+				return
+			}
+
+			seen := make(map[*eventsub.ChannelChatMessage]struct{}, notifications)
+
+			for i, event := range events {
+				if expected := fmt.Sprintf("message %d", i); event.Message.Text != expected {
+					t.Errorf("notification %d: expected %q, got %q", i, expected, event.Message.Text)
+				}
+
+				if _, ok := seen[event]; ok {
+					t.Errorf("notification %d: event instance is shared", i)
+				}
+
+				seen[event] = struct{}{}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestWithOnRawNotification(t *testing.T) {
+	unsupported := bytes.ReplaceAll(chatMessageNotification(0), []byte("channel.chat.message"), []byte("channel.unknown"))
+	url := newFramesServer(t, unsupported)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		raw      RawNotification
+		reported int
+	)
+
+	c := NewClient(url,
+		WithLogger(discardLogger()),
+		WithOnError(func(ErrorEvent) {
+			reported++
+		}),
+		WithOnRawNotification(func(_ *Metadata, p *Payload) {
+			raw = p.Payload.(RawNotification)
+			cancel()
+		}))
+	_ = c.Run(ctx)
+
+	if raw.Subscription.Type != "channel.unknown" || raw.Subscription.Condition.BroadcasterUserID != "1971641" {
+		t.Errorf("unexpected raw notification subscription: %+v", raw.Subscription)
+	}
+
+	var event eventsub.ChannelChatMessage
+
+	if err := json.Unmarshal(raw.Event, &event); err != nil || event.Message.Text != "message 0" {
+		t.Errorf("unexpected raw notification event: %s", raw.Event)
+	}
+
+	if reported != 0 {
+		t.Errorf("expected no error reports, got %d", reported)
+	}
+}
+
+func TestNotificationDecodedOnlyWhenConsumed(t *testing.T) {
+	env, err := decodeEnvelope(websocket.MessageText, chatMessageNotification(0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(websocketTwitch, WithLogger(discardLogger()))
+	p, _, err := notificationMessageHandler(c, &env.Metadata, env.Payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := p.Payload.(Notification); n.Event != nil || n.Subscription.Type != "channel.chat.message" {
+		t.Errorf("expected subscription without event, got %+v", n)
+	}
+
+	On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelChatMessage) {})
+	p, _, err = notificationMessageHandler(c, &env.Metadata, env.Payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event, ok := p.Payload.(Notification).Event.(*eventsub.ChannelChatMessage); !ok || event.Message.Text != "message 0" {
+		t.Errorf("expected decoded event, got %#v", p.Payload)
+	}
+}
+
+// unmarshalTestNotification decodes the notification message along with its event.
+func unmarshalTestNotification(data []byte, rc registryChain) (Notification, error) {
+	env, err := decodeEnvelope(websocket.MessageText, data)
+
+	if err != nil {
+		return Notification{}, err
+	}
+
+	raw, err := decodeRawNotification(env.Payload)
+
+	if err != nil {
+		return Notification{}, err
+	}
+
+	return decodeNotification(raw, rc, eventDecodingEager)
+}
+
+// newTestServer starts a WebSocket server that sends a welcome message followed by the specified number
+// of channel.chat.message notifications to every connected client. Returns the server WebSocket URL.
+func newTestServer(t *testing.T, notifications int) string {
+	t.Helper()
+
+	frames := make([][]byte, 0, notifications)
+
+	for i := range notifications {
+		frames = append(frames, chatMessageNotification(i))
+	}
+
+	return newFramesServer(t, frames...)
+}
+
+// newFramesServer starts a twitchwstest server that sends the specified text frames following the welcome message
+// to every connected client. Returns the server WebSocket URL.
+func newFramesServer(t *testing.T, frames ...[]byte) string {
+	t.Helper()
+
+	srv := twitchwstest.NewServer(twitchwstest.WithSessionHandler(func(s *twitchwstest.Session) {
+		for _, frame := range frames {
+			if err := s.SendRaw(frame); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL()
+}
+
+// welcomeMessage returns a session_welcome message with the current timestamp.
+func welcomeMessage() []byte {
+	return []byte(fmt.Sprintf(`{
+		"metadata": {
+			"message_id": "96a3f3b5-5dec-4eed-908e-e11ee657416c",
+			"message_type": "session_welcome",
+			"message_timestamp": %q
+		},
+		"payload": {
+			"session": {
+				"id": "AQoQILE98gtqShGmLD7AM6yJThAB",
+				"status": "connected",
+				"connected_at": %[1]q,
+				"keepalive_timeout_seconds": 10,
+				"reconnect_url": null
+			}
+		}
+	}`, time.Now().UTC().Format(time.RFC3339Nano)))
+}
+
+// chatMessageNotification returns a channel.chat.message notification with the message text based on the index.
+func chatMessageNotification(i int) []byte {
+	return []byte(fmt.Sprintf(`{
+		"metadata": {
+			"message_id": "notification-%[1]d",
+			"message_type": "notification",
+			"message_timestamp": %[2]q,
+			"subscription_type": "channel.chat.message",
+			"subscription_version": "1"
+		},
+		"payload": {
+			"subscription": {
+				"id": "0b7f3361-672b-4d39-b307-dd5b576c9b27",
+				"status": "enabled",
+				"type": "channel.chat.message",
+				"version": "1",
+				"condition": {
+					"broadcaster_user_id": "1971641",
+					"user_id": "2914196"
+				},
+				"transport": {
+					"method": "websocket",
+					"session_id": "AQoQILE98gtqShGmLD7AM6yJThAB"
+				},
+				"created_at": "2023-11-06T18:11:47.492253549Z",
+				"cost": 0
+			},
+			"event": {
+				"broadcaster_user_id": "1971641",
+				"broadcaster_user_login": "streamer",
+				"broadcaster_user_name": "streamer",
+				"chatter_user_id": "4145994",
+				"chatter_user_login": "viewer32",
+				"chatter_user_name": "viewer32",
+				"message_id": "cc106a89-1814-919d-454c-f4f2f970aae7",
+				"message": {
+					"text": "message %[1]d",
+					"fragments": [{"type": "text", "text": "message %[1]d"}]
+				},
+				"color": "#00FF7F",
+				"badges": [],
+				"message_type": "text"
+			}
+		}
+	}`, i, time.Now().UTC().Format(time.RFC3339Nano)))
+}
+
+// discardLogger returns a logger that drops all the records.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// noopDedupStore never reports duplicates, so benchmarks can handle the same message repeatedly.
+type noopDedupStore struct{}
+
+func (noopDedupStore) SeenOrMark(string, time.Duration) bool {
+	return false
+}
+
+func BenchmarkHandleNotification(b *testing.B) {
+	data := chatMessageNotification(0)
+	c := NewClient(websocketTwitch,
+		WithLogger(discardLogger()),
+		WithDedupStore(noopDedupStore{}),
+		WithOnNotification(func(*Metadata, *Payload) {}))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		if err := c.handleMessage(ctx, websocket.MessageText, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHandleNotificationUnhandled(b *testing.B) {
+	data := chatMessageNotification(0)
+	c := NewClient(websocketTwitch, WithLogger(discardLogger()), WithDedupStore(noopDedupStore{}))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		if err := c.handleMessage(ctx, websocket.MessageText, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHandleNotificationLazy(b *testing.B) {
+	data := chatMessageNotification(0)
+	c := NewClient(websocketTwitch,
+		WithLogger(discardLogger()),
+		WithDedupStore(noopDedupStore{}),
+		WithLazyDecoding(),
+		WithOnNotification(func(*Metadata, *Payload) {}))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		if err := c.handleMessage(ctx, websocket.MessageText, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// decodeNotificationFourPass decodes a notification the way the client did before the single pass decoding, as
+// the baseline of BenchmarkDecodeNotification: the metadata, the payload, the notification and the event are each
+// unmarshalled in a separate pass over the data of the previous one.
+func decodeNotificationFourPass(data []byte, rc registryChain) (*Metadata, Notification, error) {
+	var m struct {
+		Metadata `json:"metadata"`
+	}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, Notification{}, err
+	}
+
+	var msg json.RawMessage
+
+	if err := unmarshalEnvelope(data, &Payload{Payload: &msg}); err != nil {
+		return nil, Notification{}, err
+	}
+
+	var event json.RawMessage
+	notification := Notification{Event: &event}
+
+	if err := unmarshalEnvelope(msg, &notification); err != nil {
+		return nil, Notification{}, err
+	}
+
+	scope, err := rc.lookup(notification.Subscription.Type, notification.Subscription.Version)
+
+	if err != nil {
+		return nil, Notification{}, err
+	}
+
+	notification.Event = scope.New()
+
+	if err = unmarshalEnvelope(event, notification.Event); err != nil {
+		return nil, Notification{}, err
+	}
+
+	return &m.Metadata, notification, nil
+}
+
+func BenchmarkDecodeNotificationFourPass(b *testing.B) {
+	data := chatMessageNotification(0)
+	rc := registryChain{DefaultRegistry}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		if _, _, err := decodeNotificationFourPass(data, rc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeNotification(b *testing.B) {
+	data := chatMessageNotification(0)
+	rc := registryChain{DefaultRegistry}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		env, err := decodeEnvelope(websocket.MessageText, data)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		raw, err := decodeRawNotification(env.Payload)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err = decodeNotification(raw, rc, eventDecodingEager); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestClientSessionReconnect(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reconnected := make(chan struct{})
+	var notified []string
+
+	c := NewClient(srv.URL(),
+		WithLogger(discardLogger()),
+		WithOnReconnect(func(*Metadata, *Payload) {
+			close(reconnected)
+		}),
+		WithOnNotification(func(_ *Metadata, p *Payload) {
+			notified = append(notified, p.Payload.(Notification).Subscription.Transport.SessionID)
+
+			if len(notified) == 2 {
+				cancel()
+			}
+		}))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_ = c.Run(ctx)
+	}()
+
+	first, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := twitchwstest.Subscription{Type: "channel.chat.message"}
+
+	if _, err = first.Notify(sub, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = first.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-reconnected
+
+	select {
+	case <-first.Done():
+	case <-ctx.Done():
+		t.Fatal("previous connection has not been closed")
+	}
+
+	second, ok := srv.Session(first.ID())
+
+	if !ok || second == first {
+		t.Fatal("session has not been handed over to the reconnect URL")
+	}
+
+	if _, err = second.Notify(sub, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	<-done
+
+	if len(notified) != 2 || notified[0] != first.ID() || notified[1] != first.ID() {
+		t.Errorf("expected 2 notifications of session %q, got %v", first.ID(), notified)
+	}
+}
+
+func TestClientCloseCode(t *testing.T) {
+	srv := twitchwstest.NewServer(twitchwstest.WithSessionHandler(func(s *twitchwstest.Session) {
+		s.Close(twitchwstest.CloseConnectionUnused, "connection unused")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reason CloseReason
+
+	c := NewClient(srv.URL(), WithLogger(discardLogger()), WithOnClose(func(r CloseReason) {
+		reason = r
+		cancel()
+	}))
+	_ = c.Run(ctx)
+
+	if reason.Code != CloseConnectionUnused || reason.Reason != "connection unused" {
+		t.Errorf("unexpected close reason: %v", reason)
+	}
+}
+
+// startFaultClient runs a client of the server reconnecting right away until the test ends. Returns the channel
+// receiving the errors the client has been disconnected with.
+func startFaultClient(t *testing.T, srv *twitchwstest.Server, opts ...Option) <-chan error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 16)
+	done := make(chan struct{})
+
+	opts = append([]Option{
+		WithLogger(discardLogger()),
+		WithReconnectPolicy(&ExponentialBackoff{InitialInterval: time.Millisecond}),
+		WithOnStateChange(func(_, current ConnectionState, err error) {
+			if current != StateDisconnected || err == nil {
+				return
+			}
+
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
+	}, opts...)
+	c := NewClient(srv.URL(), opts...)
+
+	go func() {
+		defer close(done)
+		_ = c.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return errs
+}
+
+func TestClientRecoversFromFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault func(*twitchwstest.Session)
+		err   error
+	}{
+		{"skipped keepalives", (*twitchwstest.Session).PauseKeepalive, ErrKeepaliveTimeout},
+		{"delayed frames", func(s *twitchwstest.Session) { s.DelayMessages(2 * time.Second) }, ErrKeepaliveTimeout},
+		{"tcp reset", (*twitchwstest.Session).Reset, ErrRead},
+		{"binary frame", func(s *twitchwstest.Session) { _ = s.SendBinary([]byte{0x00}) }, ErrBinaryMessage},
+		{"malformed json", func(s *twitchwstest.Session) { _ = s.SendMalformed() }, ErrDecode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := twitchwstest.NewServer(
+				twitchwstest.WithKeepalive(1),
+				twitchwstest.WithKeepaliveInterval(100*time.Millisecond))
+			t.Cleanup(srv.Close)
+
+			errs := startFaultClient(t, srv)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			first, err := srv.WaitSession(ctx)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.fault(first)
+
+			if _, err = srv.WaitSession(ctx); err != nil {
+				t.Fatalf("client has not reconnected: %v", err)
+			}
+
+			select {
+			case err = <-errs:
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected disconnection with %v, got %v", tt.err, err)
+				}
+			case <-ctx.Done():
+				t.Fatal("client has not reported the disconnection")
+			}
+		})
+	}
+}
+
+type keepaliveMetrics struct {
+	NoopMetrics
+	timeouts atomic.Int32
+}
+
+func (m *keepaliveMetrics) KeepaliveTimeout() {
+	m.timeouts.Add(1)
+}
+
+func TestClientKeepaliveTimeoutMetrics(t *testing.T) {
+	srv := twitchwstest.NewServer(
+		twitchwstest.WithKeepalive(1),
+		twitchwstest.WithKeepaliveInterval(100*time.Millisecond))
+	t.Cleanup(srv.Close)
+
+	metrics := &keepaliveMetrics{}
+	errs := startFaultClient(t, srv, WithMetrics(metrics))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.PauseKeepalive()
+
+	select {
+	case err = <-errs:
+		if !errors.Is(err, ErrKeepaliveTimeout) {
+			t.Fatalf("expected disconnection with %v, got %v", ErrKeepaliveTimeout, err)
+		}
+	case <-ctx.Done():
+		t.Fatal("client has not reported the disconnection")
+	}
+
+	if timeouts := metrics.timeouts.Load(); timeouts != 1 {
+		t.Errorf("expected 1 keepalive timeout, got %d", timeouts)
+	}
+}
+
+func TestClientKeepaliveStateConcurrentAccess(t *testing.T) {
+	env, err := decodeEnvelope(websocket.MessageText, welcomeMessage())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(websocketTwitch, WithLogger(discardLogger()))
+	done := make(chan struct{})
+
+	// the reconnect goroutine handles the welcome message while the read loop checks the connection
+	go func() {
+		defer close(done)
+
+		for range 100 {
+			if _, _, err := welcomeMessageHandler(c, &env.Metadata, env.Payload); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for range 100 {
+		_ = c.isConnectionAlive()
+		_ = c.getKeepaliveTimeout()
+	}
+
+	<-done
+
+	if timeout := c.getKeepaliveTimeout(); timeout != keepaliveIntervalCalc(10) {
+		t.Errorf("expected keepalive timeout %v, got %v", keepaliveIntervalCalc(10), timeout)
+	}
+}
+
+func TestClientToleratesMessageFaults(t *testing.T) {
+	srv := twitchwstest.NewServer(twitchwstest.WithKeepalive(1), twitchwstest.WithKeepaliveInterval(100*time.Millisecond))
+	t.Cleanup(srv.Close)
+
+	var duplicates atomic.Int32
+	notified := make(chan struct{}, 2)
+
+	errs := startFaultClient(t, srv,
+		WithOnNotification(func(*Metadata, *Payload) {
+			notified <- struct{}{}
+		}),
+		WithOnDuplicate(func(*Metadata) {
+			duplicates.Add(1)
+		}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := twitchwstest.Subscription{Type: "channel.chat.message"}
+	s.ShiftTimestamps(-time.Hour)
+	s.DelayMessages(50 * time.Millisecond)
+
+	id, err := s.Notify(sub, map[string]any{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Resend(id); err != nil {
+		t.Fatal(err)
+	}
+
+	// outlive the client keepalive timeout with the shifted timestamps
+	time.Sleep(1500 * time.Millisecond)
+
+	if _, err = s.Notify(sub, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			t.Fatal("notifications have not been delivered")
+		}
+	}
+
+	if n := duplicates.Load(); n != 1 {
+		t.Errorf("expected 1 duplicate, got %d", n)
+	}
+
+	select {
+	case err = <-errs:
+		t.Errorf("unexpected disconnection: %v", err)
+	default:
+	}
+}
+
+func TestClientRedialsAfterReconnect(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	startFaultClient(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = first.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-first.Done():
+	case <-ctx.Done():
+		t.Fatal("session has not been handed over to the reconnect URL")
+	}
+
+	second, ok := srv.Session(first.ID())
+
+	if !ok || second == first {
+		t.Fatal("session has not been handed over to the reconnect URL")
+	}
+
+	// the handed over session is gone, so only a dial of the client URL starts a new one
+	second.Reset()
+
+	next, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatalf("client has not started a new session: %v", err)
+	}
+
+	if next.ID() == first.ID() {
+		t.Errorf("expected a new session, got %q again", next.ID())
+	}
+}
+
+func TestClientReconnectFaults(t *testing.T) {
+	tests := []struct {
+		name string
+		url  func(*twitchwstest.Server) string
+		err  error
+	}{
+		// the refused handshake is reported as is
+		{"refused connection", (*twitchwstest.Server).RefusingURL, nil},
+		{"no welcome message", (*twitchwstest.Server).SilentURL, ErrReconnectTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := twitchwstest.NewServer()
+			t.Cleanup(srv.Close)
+
+			errs := startFaultClient(t, srv, WithReconnectWelcomeTimeout(200*time.Millisecond))
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			first, err := srv.WaitSession(ctx)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url := tt.url(srv)
+
+			if err = first.ReconnectTo(url); err != nil {
+				t.Fatal(err)
+			}
+
+			first.Close(twitchwstest.CloseReconnectGraceTimeExpired, "reconnect grace time expired")
+
+			if _, err = srv.WaitSession(ctx); err != nil {
+				t.Fatalf("client has not started a new session: %v", err)
+			}
+
+			select {
+			case err = <-errs:
+				var re *ReconnectError
+
+				if !errors.As(err, &re) || re.URL != url || (tt.err != nil && !errors.Is(err, tt.err)) ||
+					(tt.err == nil && errors.Is(err, ErrReconnectTimeout)) {
+					t.Errorf("expected reconnect error with %v, got %v", tt.err, err)
+				}
+			case <-ctx.Done():
+				t.Fatal("client has not reported the failed reconnect")
+			}
+		})
+	}
+}
+
+func TestClientClosesFailedReconnectConnection(t *testing.T) {
+	closed := make(chan struct{})
+	invalid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = conn.CloseNow()
+		}()
+
+		// send an invalid welcome and wait for the client to give up on the connection
+		if err = conn.Write(r.Context(), websocket.MessageText, []byte("{")); err != nil {
+			return
+		}
+
+		_, _, _ = conn.Read(context.Background())
+		close(closed)
+	}))
+	t.Cleanup(invalid.Close)
+
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	startFaultClient(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = first.ReconnectTo("ws" + strings.TrimPrefix(invalid.URL, "http")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		t.Fatal("client has not closed the reconnect connection")
+	}
+}
+
+func TestClientEmulatorSubscription(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	subscribed := make(chan error, 1)
+	c := NewClient(srv.URL(), WithLogger(discardLogger()), WithOnWelcome(func(_ *Metadata, p *Payload) {
+		body := fmt.Sprintf(`{"type":"channel.follow","version":"2",`+
+			`"condition":{"broadcaster_user_id":"1337","moderator_user_id":"1337"},`+
+			`"transport":{"method":"websocket","session_id":%q}}`, p.Payload.(Session).ID)
+		resp, err := http.Post(srv.HelixURL()+"/eventsub/subscriptions", "application/json", strings.NewReader(body))
+
+		if err == nil {
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != http.StatusAccepted {
+				err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+			}
+		}
+
+		subscribed <- err
+	}))
+
+	var follow eventsub.ChannelFollowEvent
+
+	On(c, func(_ context.Context, _ *Metadata, _ EventsubSubscription, e *eventsub.ChannelFollowEvent) {
+		follow = *e
+		cancel()
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_ = c.Run(ctx)
+	}()
+
+	if err := <-subscribed; err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := srv.Trigger("channel.follow", eventsub.ChannelFollowEvent{BroadcasterUserID: "1337", UserID: "42"})
+
+	if err != nil || sent != 1 {
+		t.Fatalf("expected 1 notification, got %d, %v", sent, err)
+	}
+
+	<-done
+
+	if follow.BroadcasterUserID != "1337" || follow.UserID != "42" {
+		t.Errorf("unexpected event: %+v", follow)
+	}
+}
+
+func TestClientRunCancelled(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewClient(srv.URL(), WithLogger(discardLogger()), WithOnWelcome(func(*Metadata, *Payload) {
+		cancel()
+	}))
+
+	if err := c.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if state := c.State(); state != StateInactive {
+		t.Errorf("expected state %v, got %v", StateInactive, state)
+	}
+}
+
+func TestClientRunTerminalError(t *testing.T) {
+	stopped := httptest.NewServer(http.NotFoundHandler())
+	url := "ws" + strings.TrimPrefix(stopped.URL, "http")
+	stopped.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := NewClient(url, WithLogger(discardLogger()))
+
+	if err := c.Run(ctx); !errors.Is(err, ErrConnectionFailed) {
+		t.Errorf("expected %v, got %v", ErrConnectionFailed, err)
+	}
+}
+
+func TestClientAlreadyInUse(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := NewClient(srv.URL(), WithLogger(discardLogger()))
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	if _, err := srv.WaitSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ConnectContext(ctx); !errors.Is(err, ErrAlreadyInUse) {
+		t.Errorf("expected %v from ConnectContext, got %v", ErrAlreadyInUse, err)
+	}
+
+	if err := c.Run(ctx); !errors.Is(err, ErrAlreadyInUse) {
+		t.Errorf("expected %v from Run, got %v", ErrAlreadyInUse, err)
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
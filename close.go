@@ -0,0 +1,127 @@
+package twitchws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/coder/websocket"
+)
+
+// CloseCode represents a WebSocket close status code received from the server.
+type CloseCode int
+
+// Close codes used by Twitch when it closes an EventSub WebSocket connection.
+const (
+	CloseInternalServerError       CloseCode = 4000 // Indicates a problem with the server.
+	CloseClientSentInboundTraffic  CloseCode = 4001 // Sending outgoing messages to the server is prohibited except pong messages.
+	CloseClientFailedPingPong      CloseCode = 4002 // The client failed to respond to a ping message.
+	CloseConnectionUnused          CloseCode = 4003 // No subscription was created within the required time after connecting.
+	CloseReconnectGraceTimeExpired CloseCode = 4004 // The client failed to reconnect within the required time of a reconnect message.
+	CloseNetworkTimeout            CloseCode = 4005 // Transient network timeout.
+	CloseNetworkError              CloseCode = 4006 // Transient network error.
+	CloseInvalidReconnect          CloseCode = 4007 // The reconnect URL is invalid.
+)
+
+// closeCodeNames maps Twitch close codes to their human-readable names.
+var closeCodeNames = map[CloseCode]string{
+	CloseInternalServerError:       "internal server error",
+	CloseClientSentInboundTraffic:  "client sent inbound traffic",
+	CloseClientFailedPingPong:      "client failed ping-pong",
+	CloseConnectionUnused:          "connection unused",
+	CloseReconnectGraceTimeExpired: "reconnect grace time expired",
+	CloseNetworkTimeout:            "network timeout",
+	CloseNetworkError:              "network error",
+	CloseInvalidReconnect:          "invalid reconnect",
+}
+
+// String returns the human-readable name of the close code.
+func (c CloseCode) String() string {
+	if name, ok := closeCodeNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("status %d", int(c))
+}
+
+// CloseReason describes why the server closed the WebSocket connection.
+type CloseReason struct {
+	Code   CloseCode
+	Reason string
+}
+
+// String returns the close code along with the reason reported by the server.
+func (r CloseReason) String() string {
+	if r.Reason == "" {
+		return fmt.Sprintf("%d (%s)", int(r.Code), r.Code)
+	}
+
+	return fmt.Sprintf("%d (%s): %s", int(r.Code), r.Code, r.Reason)
+}
+
+// closeRecovery defines how the client recovers from the connection being closed by the server.
+type closeRecovery int
+
+const (
+	// recoveryRetry reconnects to the URL the client was created with according to the reconnect policy.
+	recoveryRetry closeRecovery = iota
+
+	// recoveryFreshSession starts a fresh session since the server rejected the current one. Like recoveryRetry,
+	// it reconnects to the URL the client was created with.
+	recoveryFreshSession
+
+	// recoveryStop stops the client since reconnecting would not fix the issue.
+	recoveryStop
+)
+
+// recovery returns the recovery behavior for the close reason.
+func (r CloseReason) recovery() closeRecovery {
+	switch r.Code {
+	case CloseClientSentInboundTraffic:
+		return recoveryStop
+	case CloseConnectionUnused, CloseReconnectGraceTimeExpired, CloseInvalidReconnect:
+		return recoveryFreshSession
+	default:
+		return recoveryRetry
+	}
+}
+
+// closeReasonFromError extracts the close reason from an error returned by a WebSocket read.
+// Returns false if the connection was not closed with a close frame.
+func closeReasonFromError(err error) (CloseReason, bool) {
+	var ce websocket.CloseError
+
+	if !errors.As(err, &ce) {
+		return CloseReason{}, false
+	}
+
+	return CloseReason{Code: CloseCode(ce.Code), Reason: ce.Reason}, true
+}
+
+// handleClose inspects a WebSocket read error for a close frame, reports it to the onClose callback
+// and prepares the client for the recovery required by the close code.
+// Returns the recovery behavior and true if the error was caused by a close frame.
+func (c *Client) handleClose(err error) (closeRecovery, bool) {
+	reason, ok := closeReasonFromError(err)
+
+	if !ok {
+		return recoveryRetry, false
+	}
+
+	recovery := reason.recovery()
+	level := slog.LevelInfo
+
+	if recovery == recoveryStop {
+		level = slog.LevelError
+	}
+
+	c.sessionLogger().Log(context.Background(), level, "Connection closed by server",
+		"code", int(reason.Code), "reason", reason.String())
+
+	if c.onClose != nil {
+		c.onClose(reason)
+	}
+
+	return recovery, true
+}
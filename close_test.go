@@ -0,0 +1,60 @@
+package twitchws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+func TestCloseReasonFromError(t *testing.T) {
+	err := errors.Join(websocket.CloseError{Code: 4003, Reason: "connection unused"}, ErrRead)
+	reason, ok := closeReasonFromError(err)
+
+	if !ok {
+		t.Fatal("expected close reason to be extracted")
+	}
+
+	expected := CloseReason{Code: CloseConnectionUnused, Reason: "connection unused"}
+
+	if reason != expected {
+		t.Errorf("expected %v, got %v", expected, reason)
+	}
+
+	if _, ok = closeReasonFromError(ErrRead); ok {
+		t.Error("expected no close reason for a plain read error")
+	}
+}
+
+func TestCloseReasonRecovery(t *testing.T) {
+	tests := []struct {
+		code     CloseCode
+		expected closeRecovery
+	}{
+		{CloseInternalServerError, recoveryRetry},
+		{CloseClientSentInboundTraffic, recoveryStop},
+		{CloseClientFailedPingPong, recoveryRetry},
+		{CloseConnectionUnused, recoveryFreshSession},
+		{CloseReconnectGraceTimeExpired, recoveryFreshSession},
+		{CloseNetworkTimeout, recoveryRetry},
+		{CloseNetworkError, recoveryRetry},
+		{CloseInvalidReconnect, recoveryFreshSession},
+		{CloseCode(websocket.StatusGoingAway), recoveryRetry},
+	}
+
+	for _, tt := range tests {
+		if actual := (CloseReason{Code: tt.code}).recovery(); actual != tt.expected {
+			t.Errorf("%v: expected recovery %d, got %d", tt.code, tt.expected, actual)
+		}
+	}
+}
+
+func TestCloseReasonString(t *testing.T) {
+	if s := (CloseReason{Code: CloseNetworkError}).String(); s != "4006 (network error)" {
+		t.Errorf("unexpected string: %s", s)
+	}
+
+	if s := (CloseReason{Code: 1001, Reason: "bye"}).String(); s != "1001 (status 1001): bye" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
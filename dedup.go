@@ -0,0 +1,225 @@
+package twitchws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// DedupStore tracks received message IDs in order to suppress messages redelivered by Twitch.
+// Implementations must be safe for concurrent use.
+type DedupStore interface {
+	// SeenOrMark reports whether the message ID has already been marked within its TTL.
+	// Otherwise, the message ID is marked as seen for the given TTL and false is returned.
+	SeenOrMark(id string, ttl time.Duration) bool
+}
+
+// MemoryDedupStore is an in-process DedupStore backed by a TTL cache. It is used by the client by default.
+type MemoryDedupStore struct {
+	cache *ttlcache.Cache[string, struct{}]
+}
+
+// NewMemoryDedupStore creates a new empty in-process DedupStore.
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		cache: ttlcache.New[string, struct{}](
+			ttlcache.WithDisableTouchOnHit[string, struct{}](),
+		),
+	}
+}
+
+// SeenOrMark implements DedupStore.
+func (s *MemoryDedupStore) SeenOrMark(id string, ttl time.Duration) bool {
+	s.cache.DeleteExpired()
+	_, seen := s.cache.GetOrSet(id, struct{}{}, ttlcache.WithTTL[string, struct{}](ttl))
+
+	return seen
+}
+
+// FileDedupStore is a DedupStore that keeps a marker file per message ID in a directory. Marker files are created
+// exclusively and expired ones are renewed under a lock file, so several processes sharing the directory, e.g.
+// replicas on the same host or on a shared volume, deliver each message only once. A message is reported as not seen if the marker cannot be stored.
+type FileDedupStore struct {
+	dir string
+
+	// mu guards lastPrune.
+	mu sync.Mutex
+
+	// lastPrune stores the time the directory was last cleaned up from expired marker files.
+	lastPrune time.Time
+}
+
+// NewFileDedupStore creates a DedupStore that keeps its marker files in the specified directory.
+// The directory is created if it does not exist.
+func NewFileDedupStore(dir string) (*FileDedupStore, error) {
+	const dedupDirPermissions = 0o755
+
+	if err := os.MkdirAll(dir, dedupDirPermissions); err != nil {
+		return nil, err
+	}
+
+	return &FileDedupStore{dir: dir, lastPrune: time.Now()}, nil
+}
+
+// SeenOrMark implements DedupStore.
+func (s *FileDedupStore) SeenOrMark(id string, ttl time.Duration) bool {
+	s.pruneIfDue(ttl)
+	name := s.markerPath(id)
+	created, err := createMarker(name)
+
+	if created || err != nil {
+		return false
+	}
+
+	if !markerExpired(name, ttl) {
+		return true
+	}
+
+	// the marker has expired, so renew it under the lock to let only one of the sharing stores take it over
+	return refreshExpiredMarker(name, ttl)
+}
+
+// refreshExpiredMarker renews the expired marker while holding its lock and reports whether another store has
+// renewed it first.
+func refreshExpiredMarker(name string, ttl time.Duration) bool {
+	unlock, err := lockMarker(name)
+
+	if err != nil {
+		return false
+	}
+
+	defer unlock()
+
+	if !markerExpired(name, ttl) {
+		return true
+	}
+
+	now := time.Now()
+	err = os.Chtimes(name, now, now)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		// the marker has been pruned in the meantime, so compete for the creation with the other stores
+		created, err := createMarker(name)
+
+		return !created && err == nil
+	}
+
+	return false
+}
+
+// Prune removes the marker files older than the specified TTL.
+func (s *FileDedupStore) Prune(ttl time.Duration) error {
+	entries, err := os.ReadDir(s.dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(s.dir, entry.Name())
+
+		if entry.Type().IsRegular() && markerExpired(name, ttl) {
+			if err = os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// pruneIfDue cleans up expired marker files at most once per TTL.
+func (s *FileDedupStore) pruneIfDue(ttl time.Duration) {
+	s.mu.Lock()
+	due := time.Since(s.lastPrune) > ttl
+
+	if due {
+		s.lastPrune = time.Now()
+	}
+
+	s.mu.Unlock()
+
+	if due {
+		_ = s.Prune(ttl)
+	}
+}
+
+// markerPath returns the marker file path for the message ID. The ID is hashed to get a file name that is valid
+// on every platform.
+func (s *FileDedupStore) markerPath(id string) string {
+	sum := sha256.Sum256([]byte(id))
+
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// createMarker exclusively creates the marker file. Returns false and no error if the marker already exists.
+func createMarker(name string) (bool, error) {
+	const markerPermissions = 0o644
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, markerPermissions)
+
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, f.Close()
+}
+
+// lockMarker acquires the lock file of the marker and returns the function that releases it. A lock that is held
+// for too long is considered to be left by a crashed process and is removed.
+func lockMarker(name string) (func(), error) {
+	const (
+		lockPollInterval = time.Millisecond
+		lockStaleAfter   = time.Second
+	)
+
+	lock := name + ".lock"
+
+	for {
+		created, err := createMarker(lock)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if created {
+			return func() {
+				_ = os.Remove(lock)
+			}, nil
+		}
+
+		info, err := os.Stat(lock)
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, err
+		case time.Since(info.ModTime()) > lockStaleAfter:
+			_ = os.Remove(lock)
+		default:
+			time.Sleep(lockPollInterval)
+		}
+	}
+}
+
+// markerExpired reports whether the marker file has been created or renewed earlier than TTL ago.
+func markerExpired(name string, ttl time.Duration) bool {
+	info, err := os.Stat(name)
+
+	if err != nil {
+		return true
+	}
+
+	return time.Since(info.ModTime()) > ttl
+}
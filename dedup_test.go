@@ -0,0 +1,193 @@
+package twitchws
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientIsDuplicate(t *testing.T) {
+	c := NewClient(websocketTwitch, WithDedupWindow(50*time.Millisecond))
+	m := &Metadata{MessageID: "befa7b53-d79d-478f-86b9-120f112b044e"}
+
+	if c.isDuplicate(m) {
+		t.Fatal("first message must not be reported as duplicate")
+	}
+
+	if !c.isDuplicate(m) {
+		t.Fatal("redelivered message must be reported as duplicate")
+	}
+
+	if c.isDuplicate(&Metadata{MessageID: "84f60db4-8cf8-4cc1-a3fb-9c3ea6a2cba4"}) {
+		t.Fatal("message with another ID must not be reported as duplicate")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if c.isDuplicate(m) {
+		t.Fatal("message must not be reported as duplicate after the deduplication window")
+	}
+}
+
+func TestClientIsDuplicateEmptyID(t *testing.T) {
+	c := NewClient(websocketTwitch)
+
+	if c.isDuplicate(&Metadata{}) || c.isDuplicate(&Metadata{}) {
+		t.Fatal("messages without ID must never be reported as duplicate")
+	}
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	validateDedupStore(t, NewMemoryDedupStore())
+}
+
+func TestFileDedupStore(t *testing.T) {
+	store, err := NewFileDedupStore(t.TempDir())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validateDedupStore(t, store)
+}
+
+func TestFileDedupStoreShared(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewFileDedupStore(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewFileDedupStore(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.SeenOrMark("message", time.Minute) {
+		t.Fatal("first instance must not report the message as seen")
+	}
+
+	if !second.SeenOrMark("message", time.Minute) {
+		t.Fatal("second instance must report the message marked by the first one as seen")
+	}
+}
+
+func TestFileDedupStoreSharedExpiredMarker(t *testing.T) {
+	const (
+		messages = 100
+		callers  = 8
+	)
+
+	dir := t.TempDir()
+	stores := make([]*FileDedupStore, 2)
+
+	for i := range stores {
+		var err error
+
+		if stores[i], err = NewFileDedupStore(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expired := time.Now().Add(-time.Hour)
+
+	for i := range messages {
+		id := fmt.Sprintf("message-%d", i)
+		stores[0].SeenOrMark(id, time.Minute)
+
+		if err := os.Chtimes(stores[0].markerPath(id), expired, expired); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			wg        sync.WaitGroup
+			delivered atomic.Int32
+		)
+
+		start := make(chan struct{})
+
+		for j := range callers {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				<-start
+
+				if !stores[j%len(stores)].SeenOrMark(id, time.Minute) {
+					delivered.Add(1)
+				}
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		if n := delivered.Load(); n != 1 {
+			t.Fatalf("%s: expected the expired message to be delivered once, got %d", id, n)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != messages {
+		t.Fatalf("expected %d markers, got %d entries", messages, len(entries))
+	}
+}
+
+func TestFileDedupStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileDedupStore(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.SeenOrMark("message", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	if err = store.Prune(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected expired markers to be removed, %d left", len(entries))
+	}
+}
+
+func validateDedupStore(t *testing.T, store DedupStore) {
+	t.Helper()
+	const ttl = 50 * time.Millisecond
+
+	if store.SeenOrMark("first", ttl) {
+		t.Fatal("unknown message must not be reported as seen")
+	}
+
+	if !store.SeenOrMark("first", ttl) {
+		t.Fatal("marked message must be reported as seen")
+	}
+
+	if store.SeenOrMark("second", ttl) {
+		t.Fatal("message with another ID must not be reported as seen")
+	}
+
+	time.Sleep(2 * ttl)
+
+	if store.SeenOrMark("first", ttl) {
+		t.Fatal("message must not be reported as seen after its TTL")
+	}
+}
@@ -0,0 +1,194 @@
+package twitchws
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// OverflowPolicy defines how the dispatcher handles a message when the queue of its worker is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the message read loop until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest queued message to make room for the new one.
+	OverflowDropOldest
+
+	// OverflowDropNewest drops the new message.
+	OverflowDropNewest
+)
+
+// overflowPolicyNames maps overflow policies to their human-readable names.
+var overflowPolicyNames = map[OverflowPolicy]string{
+	OverflowBlock:      "block",
+	OverflowDropOldest: "drop_oldest",
+	OverflowDropNewest: "drop_newest",
+}
+
+// String returns the human-readable name of the overflow policy.
+func (p OverflowPolicy) String() string {
+	return overflowPolicyNames[p]
+}
+
+// Default values used for the zero fields of DispatcherConfig.
+const (
+	defaultDispatcherWorkers   = 4
+	defaultDispatcherQueueSize = 64
+)
+
+// DispatcherConfig configures asynchronous dispatch of message callbacks to a bounded pool of workers.
+// Messages with the same key are always handled by the same worker, so their order is preserved.
+type DispatcherConfig struct {
+	// Workers specifies the number of workers, 4 if not set.
+	Workers int
+
+	// QueueSize specifies the capacity of every worker queue, 64 if not set.
+	QueueSize int
+
+	// Overflow defines how a message is handled when the worker queue is full.
+	Overflow OverflowPolicy
+
+	// Key returns the ordering key of a message. DefaultDispatchKey is used if not set.
+	Key func(*Metadata, *Payload) string
+}
+
+// DefaultDispatchKey orders notifications and revocations per subscription type and broadcaster,
+// and all other messages per message type.
+func DefaultDispatchKey(m *Metadata, p *Payload) string {
+	var sub EventsubSubscription
+
+	switch n := p.Payload.(type) {
+	case Notification:
+		sub = n.Subscription
+	case RawNotification:
+		sub = n.Subscription
+	default:
+		return m.MessageType
+	}
+
+	broadcaster := sub.Condition.BroadcasterUserID
+
+	if broadcaster == "" {
+		broadcaster = sub.Condition.ToBroadcasterUserID
+	}
+
+	if broadcaster == "" {
+		broadcaster = sub.Condition.UserID
+	}
+
+	return sub.Type + "/" + broadcaster
+}
+
+// dispatchItem is a message callback invocation queued for a worker.
+type dispatchItem struct {
+	fn OnMessageEventFn
+	m  *Metadata
+	p  *Payload
+}
+
+// dispatcher runs message callbacks on a pool of workers with a bounded queue each.
+type dispatcher struct {
+	config  DispatcherConfig
+	metrics Metrics
+	queues  []chan dispatchItem
+	wg      sync.WaitGroup
+}
+
+// newDispatcher creates a dispatcher with the configuration defaults applied and starts its workers.
+func newDispatcher(config DispatcherConfig, metrics Metrics) *dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = defaultDispatcherWorkers
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultDispatcherQueueSize
+	}
+
+	if config.Key == nil {
+		config.Key = DefaultDispatchKey
+	}
+
+	d := &dispatcher{
+		config:  config,
+		metrics: metrics,
+		queues:  make([]chan dispatchItem, config.Workers),
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan dispatchItem, config.QueueSize)
+		d.wg.Add(1)
+
+		go d.work(i)
+	}
+
+	return d
+}
+
+// work invokes the callbacks queued for the worker until its queue is closed and drained.
+func (d *dispatcher) work(worker int) {
+	defer d.wg.Done()
+
+	queue := d.queues[worker]
+
+	for item := range queue {
+		d.metrics.DispatchQueueDepth(worker, len(queue))
+		item.fn(item.m, item.p)
+	}
+}
+
+// dispatch queues the callback invocation to the worker responsible for the message key, applying the overflow
+// policy if the queue is full. Blocking dispatch gives up once ctx is done.
+func (d *dispatcher) dispatch(ctx context.Context, item dispatchItem) {
+	worker := d.worker(d.config.Key(item.m, item.p))
+	queue := d.queues[worker]
+
+	switch d.config.Overflow {
+	case OverflowDropNewest:
+		select {
+		case queue <- item:
+		default:
+			d.metrics.DispatchDropped(OverflowDropNewest.String())
+		}
+	case OverflowDropOldest:
+		for queued := false; !queued; {
+			select {
+			case queue <- item:
+				queued = true
+			default:
+				select {
+				case <-queue:
+					d.metrics.DispatchDropped(OverflowDropOldest.String())
+				default:
+				}
+			}
+		}
+	default:
+		select {
+		case queue <- item:
+		case <-ctx.Done():
+			d.metrics.DispatchDropped(OverflowBlock.String())
+			return
+		}
+	}
+
+	d.metrics.DispatchQueueDepth(worker, len(queue))
+}
+
+// worker returns the index of the worker responsible for the key.
+func (d *dispatcher) worker(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// stop closes the worker queues and waits for the already queued callbacks to complete.
+func (d *dispatcher) stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+
+	d.wg.Wait()
+}
@@ -0,0 +1,159 @@
+package twitchws
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type dispatchMetrics struct {
+	NoopMetrics
+	dropped atomic.Int32
+}
+
+func (m *dispatchMetrics) DispatchDropped(string) {
+	m.dropped.Add(1)
+}
+
+func TestDispatcherKeyOrdering(t *testing.T) {
+	const messages = 200
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]int)
+	)
+
+	d := newDispatcher(DispatcherConfig{
+		Workers: 4,
+		Key: func(m *Metadata, _ *Payload) string {
+			return m.SubscriptionType
+		},
+	}, NoopMetrics{})
+	record := func(m *Metadata, _ *Payload) {
+		i, _ := strconv.Atoi(m.MessageID)
+
+		mu.Lock()
+		received[m.SubscriptionType] = append(received[m.SubscriptionType], i)
+		mu.Unlock()
+	}
+
+	for i := range messages {
+		m := &Metadata{MessageID: strconv.Itoa(i), SubscriptionType: "type" + strconv.Itoa(i%3)}
+		d.dispatch(context.Background(), dispatchItem{fn: record, m: m, p: &Payload{}})
+	}
+
+	d.stop()
+
+	total := 0
+
+	for key, order := range received {
+		total += len(order)
+
+		for i := 1; i < len(order); i++ {
+			if order[i] < order[i-1] {
+				t.Fatalf("key %s: messages out of order: %v", key, order)
+			}
+		}
+	}
+
+	if total != messages {
+		t.Errorf("expected %d messages, got %d", messages, total)
+	}
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected []string
+	}{
+		{OverflowDropNewest, []string{"0", "1"}},
+		{OverflowDropOldest, []string{"0", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			metrics := &dispatchMetrics{}
+			d := newDispatcher(DispatcherConfig{Workers: 1, QueueSize: 1, Overflow: tt.policy}, metrics)
+			started := make(chan struct{})
+			release := make(chan struct{})
+
+			var received []string
+
+			fn := func(m *Metadata, _ *Payload) {
+				if m.MessageID == "0" {
+					close(started)
+					<-release
+				}
+
+				received = append(received, m.MessageID)
+			}
+
+			d.dispatch(context.Background(), dispatchItem{fn: fn, m: &Metadata{MessageID: "0"}, p: &Payload{}})
+			<-started
+
+			for _, id := range []string{"1", "2"} {
+				d.dispatch(context.Background(), dispatchItem{fn: fn, m: &Metadata{MessageID: id}, p: &Payload{}})
+			}
+
+			close(release)
+			d.stop()
+
+			if len(received) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, received)
+			}
+
+			for i := range tt.expected {
+				if received[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, received)
+				}
+			}
+
+			if metrics.dropped.Load() != 1 {
+				t.Errorf("expected 1 dropped message, got %d", metrics.dropped.Load())
+			}
+		})
+	}
+}
+
+func TestDispatcherBlockCancelled(t *testing.T) {
+	metrics := &dispatchMetrics{}
+	d := newDispatcher(DispatcherConfig{Workers: 1, QueueSize: 1}, metrics)
+	release := make(chan struct{})
+	fn := func(*Metadata, *Payload) {
+		<-release
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the first message occupies the worker and the second one the queue
+	d.dispatch(ctx, dispatchItem{fn: fn, m: &Metadata{}, p: &Payload{}})
+	d.dispatch(ctx, dispatchItem{fn: fn, m: &Metadata{}, p: &Payload{}})
+	cancel()
+	d.dispatch(ctx, dispatchItem{fn: fn, m: &Metadata{}, p: &Payload{}})
+	close(release)
+	d.stop()
+
+	if metrics.dropped.Load() != 1 {
+		t.Errorf("expected 1 dropped message, got %d", metrics.dropped.Load())
+	}
+}
+
+func TestDefaultDispatchKey(t *testing.T) {
+	n := Notification{
+		Subscription: EventsubSubscription{
+			Type:      "channel.raid",
+			Condition: EventsubCondition{ToBroadcasterUserID: "1337"},
+		},
+	}
+
+	if key := DefaultDispatchKey(&Metadata{}, &Payload{Payload: n}); key != "channel.raid/1337" {
+		t.Errorf("unexpected notification key: %s", key)
+	}
+
+	m := &Metadata{MessageType: "session_keepalive"}
+
+	if key := DefaultDispatchKey(m, &Payload{Payload: struct{}{}}); key != "session_keepalive" {
+		t.Errorf("unexpected keepalive key: %s", key)
+	}
+}
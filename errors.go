@@ -0,0 +1,122 @@
+package twitchws
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned from Wait and reported with ErrorEvent. Use errors.Is to classify them.
+var (
+	// ErrRead indicates a failure to read a message from the connection.
+	ErrRead = errors.New("read error")
+
+	// ErrHandling indicates a received message that cannot be handled. It is returned along with the cause,
+	// e.g. ErrDecode or ErrUnsupportedEvent.
+	ErrHandling = errors.New("handling error")
+
+	// ErrKeepaliveTimeout indicates that no keepalive or event message has been received in time.
+	ErrKeepaliveTimeout = errors.New("keepalive timeout")
+
+	// ErrReconnectTimeout indicates that the welcome message has not been received in time on the reconnect URL.
+	ErrReconnectTimeout = errors.New("reconnect awaiting timeout")
+
+	// ErrUnsupportedEvent indicates a notification with a subscription type or version the client cannot decode.
+	ErrUnsupportedEvent = errors.New("unsupported event")
+
+	// ErrUnsupportedType indicates a notification with an unknown subscription type.
+	// It is always reported along with ErrUnsupportedEvent.
+	ErrUnsupportedType = errors.New("unsupported event type")
+
+	// ErrUnsupportedVersion indicates a notification with a known subscription type but an unknown version.
+	// It is always reported along with ErrUnsupportedEvent.
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+
+	// ErrDecode indicates a message that cannot be unmarshalled.
+	ErrDecode = errors.New("failed to decode message")
+
+	// ErrBinaryMessage indicates a binary WebSocket frame, Twitch sends text frames only. It is reported as is,
+	// neither as ErrRead nor as a *DecodeError.
+	ErrBinaryMessage = errors.New("binary message received")
+
+	// ErrUnknownMessageType indicates a message with a message type the client does not handle.
+	ErrUnknownMessageType = errors.New("unknown message type")
+)
+
+// DecodeError describes a received message that cannot be decoded. Err holds the cause, e.g. ErrDecode or
+// ErrUnsupportedEvent.
+type DecodeError struct {
+	// MessageType is the message type, empty if the message metadata cannot be decoded.
+	MessageType string
+
+	// SubscriptionType is the subscription type of a notification or revocation message.
+	SubscriptionType string
+
+	// Version is the subscription version of a notification or revocation message.
+	Version string
+
+	// Raw is the message as received from the connection.
+	Raw []byte
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error implements error.
+func (e *DecodeError) Error() string {
+	switch {
+	case e.SubscriptionType != "":
+		return fmt.Sprintf("decode %s message %s v%s: %v", e.MessageType, e.SubscriptionType, e.Version, e.Err)
+	case e.MessageType != "":
+		return fmt.Sprintf("decode %s message: %v", e.MessageType, e.Err)
+	default:
+		return fmt.Sprintf("decode message: %v", e.Err)
+	}
+}
+
+// Unwrap returns the cause of the failure.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ReconnectError describes a failed handover to the reconnect URL requested with a session_reconnect message.
+type ReconnectError struct {
+	// URL is the reconnect URL.
+	URL string
+
+	// Err is the cause of the failure, e.g. ErrReconnectTimeout.
+	Err error
+}
+
+// Error implements error.
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("reconnect to %s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *ReconnectError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorEvent describes an error that occurred while handling a received message.
+type ErrorEvent struct {
+	// Err is the error, a *DecodeError for the messages that cannot be decoded, ErrBinaryMessage, ErrUnknownMessageType or
+	// a *PanicError for a callback panic recovered with WithRecovery.
+	// Use errors.Is to classify it, e.g. as ErrUnsupportedEvent or ErrDecode.
+	Err error
+
+	// Metadata is the metadata of the message, nil if it cannot be decoded or the error is not related to one.
+	Metadata *Metadata
+
+	// Raw is the message as received from the connection, nil for a recovered panic.
+	Raw []byte
+}
+
+// OnErrorFn defines a callback function to be executed when a received message cannot be handled.
+type OnErrorFn func(ErrorEvent)
+
+// reportError passes the error to the OnError callback, if configured.
+func (c *Client) reportError(err error, m *Metadata, raw []byte) {
+	if c.onError != nil {
+		c.onError(ErrorEvent{Err: err, Metadata: m, Raw: raw})
+	}
+}
@@ -0,0 +1,128 @@
+package twitchws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+func TestUnmarshalNotificationErrors(t *testing.T) {
+	valid := chatMessageNotification(0)
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []error
+	}{
+		{"unsupported type", bytes.ReplaceAll(valid, []byte("channel.chat.message"), []byte("channel.unknown")), []error{ErrUnsupportedEvent}},
+		{"unsupported version", bytes.ReplaceAll(valid, []byte(`"version": "1"`), []byte(`"version": "42"`)), []error{ErrUnsupportedEvent, ErrUnsupportedVersion}},
+		{"malformed", valid[:len(valid)/2], []error{ErrDecode}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unmarshalTestNotification(tt.data, registryChain{DefaultRegistry})
+
+			for _, expected := range tt.expected {
+				if !errors.Is(err, expected) {
+					t.Errorf("expected %v to match %v", err, expected)
+				}
+			}
+
+			if len(tt.expected) == 1 && errors.Is(err, ErrUnsupportedVersion) {
+				t.Errorf("unexpected %v match for %v", ErrUnsupportedVersion, err)
+			}
+		})
+	}
+}
+
+func TestGetMessageMetadataErrors(t *testing.T) {
+	if _, err := decodeEnvelope(websocket.MessageBinary, welcomeMessage()); !errors.Is(err, ErrBinaryMessage) {
+		t.Errorf("expected %v, got %v", ErrBinaryMessage, err)
+	}
+
+	var decodeErr *DecodeError
+	err := NewClient(websocketTwitch).handleMessage(context.Background(), websocket.MessageBinary, welcomeMessage())
+
+	if !errors.Is(err, ErrBinaryMessage) || errors.Is(err, ErrRead) || errors.Is(err, ErrDecode) || errors.As(err, &decodeErr) {
+		t.Errorf("expected binary message to be classified as %v only, got %v", ErrBinaryMessage, err)
+	}
+
+	if _, err := decodeEnvelope(websocket.MessageText, []byte("{")); !errors.Is(err, ErrDecode) {
+		t.Errorf("expected %v, got %v", ErrDecode, err)
+	}
+}
+
+func TestWithOnError(t *testing.T) {
+	unsupported := bytes.ReplaceAll(chatMessageNotification(0), []byte("channel.chat.message"), []byte("channel.unknown"))
+	unknown := []byte(`{"metadata": {"message_id": "unknown-1", "message_type": "session_unknown"}, "payload": {}}`)
+	url := newFramesServer(t, unsupported, unknown, chatMessageNotification(1))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var events []ErrorEvent
+
+	c := NewClient(url,
+		WithLogger(discardLogger()),
+		WithOnError(func(ev ErrorEvent) {
+			events = append(events, ev)
+		}),
+		WithOnNotification(func(*Metadata, *Payload) {
+			cancel()
+		}))
+	_ = c.Run(ctx)
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 error events, got %d: %v", len(events), events)
+	}
+
+	if !errors.Is(events[0].Err, ErrUnsupportedEvent) || !errors.Is(events[0].Err, ErrUnsupportedType) ||
+		events[0].Metadata.SubscriptionType != "channel.unknown" || !bytes.Equal(events[0].Raw, unsupported) {
+		t.Errorf("unexpected unsupported event report: %+v", events[0])
+	}
+
+	var decodeErr *DecodeError
+
+	if !errors.As(events[0].Err, &decodeErr) || decodeErr.SubscriptionType != "channel.unknown" || decodeErr.Version != "1" {
+		t.Errorf("expected *DecodeError with subscription details, got %v", events[0].Err)
+	}
+
+	if !errors.Is(events[1].Err, ErrUnknownMessageType) || events[1].Metadata.MessageType != "session_unknown" ||
+		!bytes.Equal(events[1].Raw, unknown) {
+		t.Errorf("unexpected unknown message type report: %+v", events[1])
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+		cause    error
+	}{
+		{
+			&DecodeError{MessageType: "notification", SubscriptionType: "channel.follow", Version: "2", Err: ErrDecode},
+			"decode notification message channel.follow v2: failed to decode message",
+			ErrDecode,
+		},
+		{&DecodeError{MessageType: "session_welcome", Err: ErrDecode}, "decode session_welcome message: failed to decode message", ErrDecode},
+		{&DecodeError{Err: ErrDecode}, "decode message: failed to decode message", ErrDecode},
+		{
+			&ReconnectError{URL: "wss://example.com/ws", Err: ErrReconnectTimeout},
+			"reconnect to wss://example.com/ws: reconnect awaiting timeout",
+			ErrReconnectTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.err.Error() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, tt.err.Error())
+		}
+
+		if !errors.Is(tt.err, tt.cause) {
+			t.Errorf("expected %v to match %v", tt.err, tt.cause)
+		}
+	}
+}
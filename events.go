@@ -0,0 +1,147 @@
+package twitchws
+
+import "sync"
+
+// Event bundles a notification or revocation message with its metadata. Metadata.MessageType tells
+// the message types apart.
+type Event struct {
+	Metadata     Metadata
+	Notification Notification
+}
+
+// LifecycleEvent describes a connection state transition along with the error that caused it, if any.
+type LifecycleEvent struct {
+	Previous ConnectionState
+	Current  ConnectionState
+	Err      error
+}
+
+// eventStream holds the channels of a single client run.
+type eventStream struct {
+	events    chan Event
+	lifecycle chan LifecycleEvent
+	closed    bool
+}
+
+// eventStreamState guards the channels the client delivers events to.
+type eventStreamState struct {
+	// mu guards stream.
+	mu sync.Mutex
+
+	// buffer specifies the capacity of the channels.
+	buffer int
+
+	// stream holds the channels of the current or the last client run.
+	stream *eventStream
+}
+
+// newEventStream creates the channels with the specified buffer size.
+func newEventStream(buffer int) *eventStream {
+	return &eventStream{
+		events:    make(chan Event, buffer),
+		lifecycle: make(chan LifecycleEvent, buffer),
+	}
+}
+
+// Events returns the channel notifications and revocations are delivered to, or nil if the event stream is not
+// enabled with WithEventStream. Delivery blocks the message read loop while the channel buffer is full, so the
+// channel must be drained continuously. The channel is closed once the client stops; connecting the client
+// again opens a new channel, so Events has to be called again after that.
+func (c *Client) Events() <-chan Event {
+	stream := c.currentEventStream()
+
+	if stream == nil {
+		return nil
+	}
+
+	return stream.events
+}
+
+// Lifecycle returns the channel connection state transitions are delivered to, or nil if the event stream is not
+// enabled with WithEventStream. Transitions are dropped while the channel buffer is full, use State to get the
+// current state at any time. The channel is closed along with the Events channel.
+func (c *Client) Lifecycle() <-chan LifecycleEvent {
+	stream := c.currentEventStream()
+
+	if stream == nil {
+		return nil
+	}
+
+	return stream.lifecycle
+}
+
+// currentEventStream returns the channels of the current or the last client run.
+func (c *Client) currentEventStream() *eventStream {
+	if c.streamState == nil {
+		return nil
+	}
+
+	c.streamState.mu.Lock()
+	defer c.streamState.mu.Unlock()
+
+	return c.streamState.stream
+}
+
+// openEventStream creates new channels if the previous ones have been closed by the client shutdown.
+func (c *Client) openEventStream() {
+	if c.streamState == nil {
+		return
+	}
+
+	c.streamState.mu.Lock()
+	defer c.streamState.mu.Unlock()
+
+	if c.streamState.stream == nil || c.streamState.stream.closed {
+		c.streamState.stream = newEventStream(c.streamState.buffer)
+	}
+}
+
+// closeEventStream closes the channels once the client has stopped.
+func (c *Client) closeEventStream() {
+	if c.streamState == nil {
+		return
+	}
+
+	c.streamState.mu.Lock()
+	defer c.streamState.mu.Unlock()
+
+	stream := c.streamState.stream
+	stream.closed = true
+	close(stream.events)
+	close(stream.lifecycle)
+}
+
+// publishEvent delivers the notification or revocation to the Events channel, blocking while its buffer is full
+// until the client is stopped.
+func (c *Client) publishEvent(m *Metadata, p *Payload) {
+	stream := c.currentEventStream()
+
+	if stream == nil {
+		return
+	}
+
+	n, ok := p.Payload.(Notification)
+
+	if !ok {
+		return
+	}
+
+	select {
+	case stream.events <- Event{Metadata: *m, Notification: n}:
+	case <-c.mainContext().Done():
+	}
+}
+
+// publishLifecycle delivers the state transition to the Lifecycle channel unless its buffer is full.
+func (c *Client) publishLifecycle(previous, current ConnectionState, err error) {
+	stream := c.currentEventStream()
+
+	if stream == nil {
+		return
+	}
+
+	select {
+	case stream.lifecycle <- LifecycleEvent{Previous: previous, Current: current, Err: err}:
+	default:
+	}
+}
@@ -0,0 +1,75 @@
+package twitchws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+)
+
+func TestClientEvents(t *testing.T) {
+	const notifications = 10
+
+	url := newTestServer(t, notifications)
+	c := NewClient(url, WithLogger(discardLogger()), WithEventStream(16))
+	events := c.Events()
+	lifecycle := c.Lifecycle()
+
+	if events == nil || lifecycle == nil {
+		t.Fatal("expected event stream channels to be available before connecting")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	for i := range notifications {
+		ev, ok := <-events
+
+		if !ok {
+			t.Fatalf("events channel closed after %d notifications", i)
+		}
+
+		msg := ev.Notification.Event.(*eventsub.ChannelChatMessage)
+
+		if ev.Metadata.MessageType != "notification" || msg.Message.Text != fmt.Sprintf("message %d", i) {
+			t.Errorf("unexpected event %d: %+v", i, ev)
+		}
+	}
+
+	cancel()
+	<-done
+
+	if _, ok := <-events; ok {
+		t.Error("expected events channel to be closed once the client stopped")
+	}
+
+	var states []ConnectionState
+
+	for ev := range lifecycle {
+		states = append(states, ev.Current)
+	}
+
+	if len(states) == 0 || states[0] != StateConnecting || states[len(states)-1] != StateInactive {
+		t.Errorf("unexpected lifecycle states: %v", states)
+	}
+
+	if c.Events() != events {
+		t.Error("expected closed events channel to be kept until the client connects again")
+	}
+}
+
+func TestClientEventsDisabled(t *testing.T) {
+	c := NewClient(websocketTwitch)
+
+	if c.Events() != nil || c.Lifecycle() != nil {
+		t.Error("expected no event stream channels unless enabled")
+	}
+}
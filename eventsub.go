@@ -1,279 +1,268 @@
 package twitchws
 
-import (
-	"errors"
-
-	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
-)
+import "github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
 
+// EventSubScope describes a supported version of an EventSub subscription type.
 type EventSubScope struct {
-	Version       string
-	MsgType       interface{}
-	ConditionType interface{}
-}
+	Version string
 
-var (
-	// errEventSubNotFound indicates that the requested EventSub configuration was not found in the available definitions.
-	errEventSubNotFound = errors.New("eventsub not found")
+	// New creates a new event instance to unmarshal a notification into. Nil if the version has no event struct yet.
+	New func() any
 
-	// errEventSubVersion indicates that the requested EventSub version was not found.
-	errEventSubVersion = errors.New("eventsub version not found")
-)
+	ConditionType interface{}
+}
 
 // eventSubTypes defines a mapping of event types to eventSubScope slices, specifying different versions and message types for each event.
 var (
 	eventSubTypes = map[string][]EventSubScope{
 		"automod.message.hold": {
-			{Version: "1", MsgType: nil},
-			{Version: "2", MsgType: nil},
+			{Version: "1"},
+			{Version: "2"},
 		},
 		"automod.message.update": {
-			{Version: "1", MsgType: nil},
-			{Version: "2", MsgType: nil},
+			{Version: "1"},
+			{Version: "2"},
 		},
 		"automod.settings.update": {
-			{Version: "1", MsgType: &eventsub.AutomodSettingsUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.AutomodSettingsUpdateEvent]},
 		},
 		"automod.terms.update": {
-			{Version: "1", MsgType: &eventsub.AutomodTermsUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.AutomodTermsUpdateEvent]},
 		},
 		"channel.ad_break.begin": {
-			{Version: "1", MsgType: &eventsub.ChannelAdBreakBeginEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelAdBreakBeginEvent]},
 		},
 		"channel.ban": {
-			{Version: "1", MsgType: &eventsub.ChannelBanEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelBanEvent]},
 		},
 		"channel.channel_points_automatic_reward_redemption.add": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsAutomaticRewardRedemptionAddEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsAutomaticRewardRedemptionAddEvent]},
 		},
 		"channel.channel_points_custom_reward.add": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsCustomRewardAddEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsCustomRewardAddEvent]},
 		},
 		"channel.channel_points_custom_reward.remove": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsCustomRewardRemoveEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsCustomRewardRemoveEvent]},
 		},
 		"channel.channel_points_custom_reward.update": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsCustomRewardUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsCustomRewardUpdateEvent]},
 		},
 		"channel.channel_points_custom_reward_redemption.add": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsCustomRewardRedemptionAddEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsCustomRewardRedemptionAddEvent]},
 		},
 		"channel.channel_points_custom_reward_redemption.update": {
-			{Version: "1", MsgType: &eventsub.ChannelPointsCustomRewardRedemptionUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPointsCustomRewardRedemptionUpdateEvent]},
 		},
 		"channel.charity_campaign.donate": {
-			{Version: "1", MsgType: &eventsub.CharityDonationEvent{}},
+			{Version: "1", New: newEvent[eventsub.CharityDonationEvent]},
 		},
 		"channel.charity_campaign.progress": {
-			{Version: "1", MsgType: &eventsub.CharityCampaignProgressEvent{}},
+			{Version: "1", New: newEvent[eventsub.CharityCampaignProgressEvent]},
 		},
 		"channel.charity_campaign.start": {
-			{Version: "1", MsgType: &eventsub.CharityCampaignStartEvent{}},
+			{Version: "1", New: newEvent[eventsub.CharityCampaignStartEvent]},
 		},
 		"channel.charity_campaign.stop": {
-			{Version: "1", MsgType: &eventsub.CharityCampaignStopEvent{}},
+			{Version: "1", New: newEvent[eventsub.CharityCampaignStopEvent]},
 		},
 		"channel.chat.clear": {
-			{Version: "1", MsgType: &eventsub.ChannelChatClearEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelChatClearEvent]},
 		},
 		"channel.chat.clear_user_messages": {
-			{Version: "1", MsgType: &eventsub.ChannelChatClearUserMessagesEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelChatClearUserMessagesEvent]},
 		},
 		"channel.chat.message": {
-			{Version: "1", MsgType: &eventsub.ChannelChatMessage{}},
+			{Version: "1", New: newEvent[eventsub.ChannelChatMessage]},
 		},
 		"channel.chat.message_delete": {
-			{Version: "1", MsgType: &eventsub.ChannelChatMessageDeleteEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelChatMessageDeleteEvent]},
 		},
 		"channel.chat.notification": {
-			{Version: "1", MsgType: nil},
+			{Version: "1"},
 		},
 		"channel.chat.user_message_hold": {
-			{Version: "1", MsgType: nil},
+			{Version: "1"},
 		},
 		"channel.chat.user_message_update": {
-			{Version: "1", MsgType: nil},
+			{Version: "1"},
 		},
 		"channel.chat_settings.update": {
-			{Version: "1", MsgType: &eventsub.ChannelChatSettingsUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelChatSettingsUpdateEvent]},
 		},
 		"channel.cheer": {
-			{Version: "1", MsgType: &eventsub.ChannelCheerEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelCheerEvent]},
 		},
 		"channel.follow": {
-			{Version: "2", MsgType: &eventsub.ChannelFollowEvent{}},
+			{Version: "2", New: newEvent[eventsub.ChannelFollowEvent]},
 		},
 		"channel.goal.begin": {
-			{Version: "1", MsgType: &eventsub.GoalsEvent{}},
+			{Version: "1", New: newEvent[eventsub.GoalsEvent]},
 		},
 		"channel.goal.end": {
-			{Version: "1", MsgType: &eventsub.GoalsEvent{}},
+			{Version: "1", New: newEvent[eventsub.GoalsEvent]},
 		},
 		"channel.goal.progress": {
-			{Version: "1", MsgType: &eventsub.GoalsEvent{}},
+			{Version: "1", New: newEvent[eventsub.GoalsEvent]},
 		},
 		"channel.guest_star_guest.update": {
-			{Version: "beta", MsgType: &eventsub.ChannelGuestStarGuestUpdateEvent{}},
+			{Version: "beta", New: newEvent[eventsub.ChannelGuestStarGuestUpdateEvent]},
 		},
 		"channel.guest_star_session.begin": {
-			{Version: "beta", MsgType: &eventsub.ChannelGuestStarSessionBeginEvent{}},
+			{Version: "beta", New: newEvent[eventsub.ChannelGuestStarSessionBeginEvent]},
 		},
 		"channel.guest_star_session.end": {
-			{Version: "beta", MsgType: &eventsub.ChannelGuestStarSessionEndEvent{}},
+			{Version: "beta", New: newEvent[eventsub.ChannelGuestStarSessionEndEvent]},
 		},
 		"channel.guest_star_settings.update": {
-			{Version: "beta", MsgType: &eventsub.ChannelGuestStarSettingsUpdateEvent{}},
+			{Version: "beta", New: newEvent[eventsub.ChannelGuestStarSettingsUpdateEvent]},
 		},
 		"channel.hype_train.begin": {
-			{Version: "1", MsgType: &eventsub.HypeTrainBeginEvent{}},
+			{Version: "1", New: newEvent[eventsub.HypeTrainBeginEvent]},
 		},
 		"channel.hype_train.end": {
-			{Version: "1", MsgType: &eventsub.HypeTrainEndEvent{}},
+			{Version: "1", New: newEvent[eventsub.HypeTrainEndEvent]},
 		},
 		"channel.hype_train.progress": {
-			{Version: "1", MsgType: &eventsub.HypeTrainProgressEvent{}},
+			{Version: "1", New: newEvent[eventsub.HypeTrainProgressEvent]},
 		},
 		"channel.moderate": {
-			{Version: "1", MsgType: &eventsub.ChannelModerateEvent{}},
-			{Version: "2", MsgType: nil},
+			{Version: "1", New: newEvent[eventsub.ChannelModerateEvent]},
+			{Version: "2"},
 		},
 		"channel.moderator.add": {
-			{Version: "1", MsgType: &eventsub.ChannelModeratorAddEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelModeratorAddEvent]},
 		},
 		"channel.moderator.remove": {
-			{Version: "1", MsgType: &eventsub.ChannelModeratorRemoveEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelModeratorRemoveEvent]},
 		},
 		"channel.poll.begin": {
-			{Version: "1", MsgType: &eventsub.ChannelPollBeginEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPollBeginEvent]},
 		},
 		"channel.poll.end": {
-			{Version: "1", MsgType: &eventsub.ChannelPollEndEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPollEndEvent]},
 		},
 		"channel.poll.progress": {
-			{Version: "1", MsgType: &eventsub.ChannelPollProgressEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPollProgressEvent]},
 		},
 		"channel.prediction.begin": {
-			{Version: "1", MsgType: &eventsub.ChannelPredictionBeginEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPredictionBeginEvent]},
 		},
 		"channel.prediction.end": {
-			{Version: "1", MsgType: &eventsub.ChannelPredictionEndEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPredictionEndEvent]},
 		},
 		"channel.prediction.lock": {
-			{Version: "1", MsgType: &eventsub.ChannelPredictionLockEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPredictionLockEvent]},
 		},
 		"channel.prediction.progress": {
-			{Version: "1", MsgType: &eventsub.ChannelPredictionProgressEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelPredictionProgressEvent]},
 		},
 		"channel.raid": {
-			{Version: "1", MsgType: &eventsub.ChannelRaidEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelRaidEvent]},
 		},
 		"channel.shared_chat.begin": {
-			{Version: "1", MsgType: &eventsub.ChannelSharedChatSessionBeginEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSharedChatSessionBeginEvent]},
 		},
 		"channel.shared_chat.end": {
-			{Version: "1", MsgType: &eventsub.ChannelSharedChatSessionEndEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSharedChatSessionEndEvent]},
 		},
 		"channel.shared_chat.update": {
-			{Version: "1", MsgType: &eventsub.ChannelSharedChatSessionUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSharedChatSessionUpdateEvent]},
 		},
 		"channel.shield_mode.begin": {
-			{Version: "1", MsgType: &eventsub.ShieldModeEvent{}},
+			{Version: "1", New: newEvent[eventsub.ShieldModeEvent]},
 		},
 		"channel.shield_mode.end": {
-			{Version: "1", MsgType: &eventsub.ShieldModeEvent{}},
+			{Version: "1", New: newEvent[eventsub.ShieldModeEvent]},
 		},
 		"channel.shoutout.create": {
-			{Version: "1", MsgType: &eventsub.ShoutoutCreateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ShoutoutCreateEvent]},
 		},
 		"channel.shoutout.receive": {
-			{Version: "1", MsgType: &eventsub.ShoutoutReceivedEvent{}},
+			{Version: "1", New: newEvent[eventsub.ShoutoutReceivedEvent]},
 		},
 		"channel.subscribe": {
-			{Version: "1", MsgType: &eventsub.ChannelSubscribeEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSubscribeEvent]},
 		},
 		"channel.subscription.end": {
-			{Version: "1", MsgType: &eventsub.ChannelSubscriptionEndEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSubscriptionEndEvent]},
 		},
 		"channel.subscription.gift": {
-			{Version: "1", MsgType: &eventsub.ChannelSubscriptionGiftEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSubscriptionGiftEvent]},
 		},
 		"channel.subscription.message": {
-			{Version: "1", MsgType: &eventsub.ChannelSubscriptionMessageEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSubscriptionMessageEvent]},
 		},
 		"channel.suspicious_user.message": {
-			{Version: "1", MsgType: &eventsub.ChannelSuspiciousUserMessageEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSuspiciousUserMessageEvent]},
 		},
 		"channel.suspicious_user.update": {
-			{Version: "1", MsgType: &eventsub.ChannelSuspiciousUserUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelSuspiciousUserUpdateEvent]},
 		},
 		"channel.unban": {
-			{Version: "1", MsgType: &eventsub.ChannelUnbanEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelUnbanEvent]},
 		},
 		"channel.unban_request.create": {
-			{Version: "1", MsgType: &eventsub.ChannelUnbanRequestCreateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelUnbanRequestCreateEvent]},
 		},
 		"channel.unban_request.resolve": {
-			{Version: "1", MsgType: &eventsub.ChannelUnbanRequestResolveEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelUnbanRequestResolveEvent]},
 		},
 		"channel.update": {
-			{Version: "2", MsgType: &eventsub.ChannelUpdateEvent{}},
+			{Version: "2", New: newEvent[eventsub.ChannelUpdateEvent]},
 		},
 		"channel.vip.add": {
-			{Version: "1", MsgType: &eventsub.ChannelVIPAddEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelVIPAddEvent]},
 		},
 		"channel.vip.remove": {
-			{Version: "1", MsgType: &eventsub.ChannelVIPRemoveEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelVIPRemoveEvent]},
 		},
 		"channel.warning.acknowledge": {
-			{Version: "1", MsgType: nil},
+			{Version: "1"},
 		},
 		"channel.warning.send": {
-			{Version: "1", MsgType: &eventsub.ChannelWarningSendEvent{}},
+			{Version: "1", New: newEvent[eventsub.ChannelWarningSendEvent]},
 		},
 		"conduit.shard.disabled": {
-			{Version: "1", MsgType: &eventsub.ConduitShardDisabledEvent{}},
+			{Version: "1", New: newEvent[eventsub.ConduitShardDisabledEvent]},
 		},
 		"drop.entitlement.grant": {
-			{Version: "1", MsgType: &eventsub.DropEntitlementGrantEvent{}},
+			{Version: "1", New: newEvent[eventsub.DropEntitlementGrantEvent]},
 		},
 		"extension.bits_transaction.create": {
-			{Version: "1", MsgType: &eventsub.ExtensionBitsTransactionCreateEvent{}},
+			{Version: "1", New: newEvent[eventsub.ExtensionBitsTransactionCreateEvent]},
 		},
 		"stream.offline": {
-			{Version: "1", MsgType: &eventsub.StreamOfflineEvent{}},
+			{Version: "1", New: newEvent[eventsub.StreamOfflineEvent]},
 		},
 		"stream.online": {
-			{Version: "1", MsgType: &eventsub.StreamOnlineEvent{}},
+			{Version: "1", New: newEvent[eventsub.StreamOnlineEvent]},
 		},
 		"user.authorization.grant": {
-			{Version: "1", MsgType: &eventsub.UserAuthorizationGrantEvent{}},
+			{Version: "1", New: newEvent[eventsub.UserAuthorizationGrantEvent]},
 		},
 		"user.authorization.revoke": {
-			{Version: "1", MsgType: &eventsub.UserAuthorizationRevokeEvent{}},
+			{Version: "1", New: newEvent[eventsub.UserAuthorizationRevokeEvent]},
 		},
 		"user.update": {
-			{Version: "1", MsgType: &eventsub.UserUpdateEvent{}},
+			{Version: "1", New: newEvent[eventsub.UserUpdateEvent]},
 		},
 		"user.whisper.message": {
-			{Version: "1", MsgType: &eventsub.WhisperReceivedEvent{}},
+			{Version: "1", New: newEvent[eventsub.WhisperReceivedEvent]},
 		},
 	}
 )
 
-func getEventSub(eventTypeKey, targetVersion string) (*EventSubScope, error) {
-	if subTypes, ok := eventSubTypes[eventTypeKey]; ok {
-		return getMatchingEventSub(subTypes, targetVersion)
-	}
-
-	return nil, errEventSubNotFound
-}
-
 func getMatchingEventSub(subTypes []EventSubScope, targetVersion string) (*EventSubScope, error) {
 	for _, subType := range subTypes {
-		if subType.Version == targetVersion && subType.MsgType != nil {
+		if subType.Version == targetVersion && subType.New != nil {
 			return &subType, nil
 		}
 	}
 
-	return nil, errEventSubVersion
+	return nil, ErrUnsupportedVersion
+}
+
+// newEvent creates a new zero value event of type T, used as EventSubScope.New factory.
+func newEvent[T any]() any {
+	return new(T)
 }
@@ -0,0 +1,168 @@
+package twitchws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// typedHandler is a handler registered with On for a set of subscription types.
+type typedHandler struct {
+	id uint64
+
+	// fn invokes the handler with the notification event, wrapped into the client middlewares.
+	fn Handler
+}
+
+// typedHandlers keeps the handlers registered with On per subscription type.
+type typedHandlers struct {
+	// mu guards nextID and handlers.
+	mu sync.RWMutex
+
+	// nextID is the ID assigned to the next registered handler.
+	nextID uint64
+
+	// handlers maps subscription types to the handlers registered for them.
+	handlers map[string][]typedHandler
+}
+
+// On registers a handler for notifications whose event is decoded into T, e.g. eventsub.ChannelFollowEvent.
+// The handler is invoked for every subscription type and version mapped to T, after the OnNotification callback.
+// Each handler is wrapped into the client middlewares separately.
+// Several handlers can be registered for the same type. Returns a function that unregisters the handler.
+// The subscription types are resolved at registration time from the client registry set with WithRegistry
+// and DefaultRegistry, so a subscription type version overridden by the client registry is no longer mapped
+// to the default event struct. On panics if T is not mapped to any subscription type.
+func On[T any](c *Client, fn func(ctx context.Context, m *Metadata, sub EventsubSubscription, event *T)) func() {
+	subscriptionTypes := subscriptionTypesOf[T](c.registries())
+
+	if len(subscriptionTypes) == 0 {
+		panic(fmt.Sprintf("twitchws: no subscription type is mapped to %T", (*T)(nil)))
+	}
+
+	id := c.handlers.add(subscriptionTypes, c.wrap(func(m *Metadata, p *Payload) {
+		if n, ok := p.Payload.(Notification); ok {
+			if e, ok := n.Event.(*T); ok {
+				fn(c.mainContext(), m, n.Subscription, e)
+			}
+		}
+	}))
+
+	return func() {
+		c.handlers.remove(subscriptionTypes, id)
+	}
+}
+
+// subscriptionTypesOf returns the subscription types which events are decoded into T. A subscription type version
+// mapped to T is skipped if a preceding registry maps it to another event struct.
+func subscriptionTypesOf[T any](rc registryChain) []string {
+	var subscriptionTypes []string
+
+	for _, r := range rc {
+		for subscriptionType, scopes := range r.snapshot() {
+			for _, scope := range scopes {
+				if scope.New == nil || !isEventOf[T](&scope) {
+					continue
+				}
+
+				if resolved, err := rc.lookup(subscriptionType, scope.Version); err == nil && isEventOf[T](resolved) {
+					subscriptionTypes = append(subscriptionTypes, subscriptionType)
+					break
+				}
+			}
+		}
+	}
+
+	slices.Sort(subscriptionTypes)
+
+	return slices.Compact(subscriptionTypes)
+}
+
+// isEventOf reports whether the scope events are decoded into T.
+func isEventOf[T any](scope *EventSubScope) bool {
+	_, ok := scope.New().(*T)
+
+	return ok
+}
+
+// add registers the handler for the subscription types and returns its ID.
+func (h *typedHandlers) add(subscriptionTypes []string, fn Handler) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = make(map[string][]typedHandler)
+	}
+
+	h.nextID++
+
+	for _, subscriptionType := range subscriptionTypes {
+		h.handlers[subscriptionType] = append(h.handlers[subscriptionType], typedHandler{id: h.nextID, fn: fn})
+	}
+
+	return h.nextID
+}
+
+// remove unregisters the handler with the ID from the subscription types.
+func (h *typedHandlers) remove(subscriptionTypes []string, id uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscriptionType := range subscriptionTypes {
+		handlers := slices.DeleteFunc(slices.Clone(h.handlers[subscriptionType]), func(handler typedHandler) bool {
+			return handler.id == id
+		})
+
+		if len(handlers) == 0 {
+			delete(h.handlers, subscriptionType)
+		} else {
+			h.handlers[subscriptionType] = handlers
+		}
+	}
+}
+
+// get returns the handlers registered for the subscription type.
+func (h *typedHandlers) get(subscriptionType string) []typedHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.handlers[subscriptionType]
+}
+
+// eventDecoding returns how the event of a notification with the subscription type is decoded. Events with
+// typed handlers are always decoded eagerly, others are decoded lazily if enabled with WithLazyDecoding.
+// Otherwise, the event is decoded only if anything can observe it: a callback, the event stream or a custom
+// dispatch key. Middlewares observe only the messages passed to the callbacks.
+func (c *Client) eventDecoding(subscriptionType string) eventDecoding {
+	switch {
+	case len(c.handlers.get(subscriptionType)) > 0:
+		return eventDecodingEager
+	case c.lazyDecoding:
+		return eventDecodingLazy
+	case c.onNotificationMessage != nil ||
+		c.streamState != nil ||
+		(c.dispatcherConfig != nil && c.dispatcherConfig.Key != nil):
+		return eventDecodingEager
+	default:
+		return eventDecodingSkip
+	}
+}
+
+// handleNotification invokes the OnNotification callback followed by the handlers registered with On
+// for the notification subscription type.
+func (c *Client) handleNotification(m *Metadata, p *Payload) {
+	if c.onNotificationMessage != nil {
+		c.onNotificationMessage(m, p)
+	}
+
+	n, ok := p.Payload.(Notification)
+
+	if !ok {
+		return
+	}
+
+	for _, handler := range c.handlers.get(n.Subscription.Type) {
+		handler.fn(m, p)
+	}
+}
@@ -0,0 +1,100 @@
+package twitchws
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+)
+
+func TestSubscriptionTypesOf(t *testing.T) {
+	if types := subscriptionTypesOf[eventsub.ChannelChatMessage](registryChain{DefaultRegistry}); !slices.Equal(types, []string{"channel.chat.message"}) {
+		t.Errorf("unexpected subscription types: %v", types)
+	}
+
+	expected := []string{"channel.goal.begin", "channel.goal.end", "channel.goal.progress"}
+
+	if types := subscriptionTypesOf[eventsub.GoalsEvent](registryChain{DefaultRegistry}); !slices.Equal(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+}
+
+func TestSubscriptionTypesOfOverridden(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Register("channel.chat.message", "1", newEvent[customChatMessage]); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := registryChain{r, DefaultRegistry}
+
+	if types := subscriptionTypesOf[eventsub.ChannelChatMessage](rc); len(types) != 0 {
+		t.Errorf("expected the overridden type not to be mapped to the default struct, got %v", types)
+	}
+
+	if types := subscriptionTypesOf[customChatMessage](rc); !slices.Equal(types, []string{"channel.chat.message"}) {
+		t.Errorf("unexpected subscription types: %v", types)
+	}
+
+	if types := subscriptionTypesOf[eventsub.ChannelFollowEvent](rc); !slices.Equal(types, []string{"channel.follow"}) {
+		t.Errorf("unexpected subscription types: %v", types)
+	}
+}
+
+func TestOnUnmappedType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected On to panic for a type without subscription types")
+		}
+	}()
+
+	On(NewClient(websocketTwitch), func(context.Context, *Metadata, EventsubSubscription, *struct{}) {})
+}
+
+func TestOn(t *testing.T) {
+	var (
+		notifications int
+		first         []string
+		second        []string
+	)
+
+	c := NewClient(websocketTwitch, WithOnNotification(func(*Metadata, *Payload) {
+		notifications++
+	}))
+	unregister := On(c, func(_ context.Context, _ *Metadata, _ EventsubSubscription, ev *eventsub.ChannelChatMessage) {
+		first = append(first, ev.Message.Text)
+	})
+	On(c, func(_ context.Context, _ *Metadata, sub EventsubSubscription, ev *eventsub.ChannelChatMessage) {
+		second = append(second, sub.Type+": "+ev.Message.Text)
+	})
+	On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelFollowEvent) {
+		t.Error("unexpected channel.follow handler invocation")
+	})
+
+	handle := func(i int) {
+		n, err := unmarshalTestNotification(chatMessageNotification(i), registryChain{DefaultRegistry})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.handleNotification(&Metadata{MessageType: "notification"}, &Payload{Payload: n})
+	}
+
+	handle(0)
+	unregister()
+	handle(1)
+
+	if notifications != 2 {
+		t.Errorf("expected OnNotification callback to be invoked twice, got %d", notifications)
+	}
+
+	if !slices.Equal(first, []string{"message 0"}) {
+		t.Errorf("unexpected first handler invocations: %v", first)
+	}
+
+	if !slices.Equal(second, []string{"channel.chat.message: message 0", "channel.chat.message: message 1"}) {
+		t.Errorf("unexpected second handler invocations: %v", second)
+	}
+}
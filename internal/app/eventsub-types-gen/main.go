@@ -23,16 +23,20 @@ const eventsubTypesFileTemplate = `package twitchws
 
 import "github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
 
-type eventSubScope struct {
-	Version       string
-	MsgType       interface{}
+// EventSubScope describes a supported version of an EventSub subscription type.
+type EventSubScope struct {
+	Version string
+
+	// New creates a new event instance to unmarshal a notification into. Nil if the version has no event struct yet.
+	New func() any
+
 	ConditionType interface{}
 }
 
 var (
-	eventSubTypes = map[string][]eventSubScope{
+	eventSubTypes = map[string][]EventSubScope{
 		{{range $name, $entries := .}}"{{$name}}": {
-			{{range $entries}}{Version: "{{.Core.Version}}", MsgType: &eventsub.{{.MessageType}}{}},
+			{{range $entries}}{Version: "{{.Core.Version}}", New: newEvent[eventsub.{{.MessageType}}]},
 			{{end}}
 		},
 		{{end}}
@@ -0,0 +1,53 @@
+package twitchws
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// eventDecoding defines how the event of a notification is decoded.
+type eventDecoding int
+
+const (
+	// eventDecodingSkip leaves the event nil as nothing can observe it.
+	eventDecodingSkip eventDecoding = iota
+
+	// eventDecodingLazy keeps the event raw until Notification.Decode is called.
+	eventDecodingLazy
+
+	// eventDecodingEager decodes the event along with the notification.
+	eventDecodingEager
+)
+
+// lazyEvent decodes a raw event once, on the first access.
+type lazyEvent struct {
+	once     sync.Once
+	raw      json.RawMessage
+	newEvent func() any
+	event    any
+	err      error
+}
+
+// decode returns the event decoded into a new instance of the registered struct, decoding it on the first call.
+func (l *lazyEvent) decode() (any, error) {
+	l.once.Do(func() {
+		event := l.newEvent()
+
+		if l.err = unmarshalEnvelope(l.raw, event); l.err == nil {
+			l.event = event
+		}
+	})
+
+	return l.event, l.err
+}
+
+// Decode returns the event decoded into the struct registered for the subscription type and version. If the event
+// is kept raw by lazy decoding, it is decoded on the first call and the result is shared by all the copies of the
+// notification. Otherwise, Event is returned as is.
+func (n Notification) Decode() (any, error) {
+	if n.lazy == nil {
+		return n.Event, nil
+	}
+
+	return n.lazy.decode()
+}
@@ -0,0 +1,71 @@
+package twitchws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coder/websocket"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+)
+
+func handleTestNotification(t *testing.T, c *Client) Notification {
+	t.Helper()
+
+	env, err := decodeEnvelope(websocket.MessageText, chatMessageNotification(0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _, err := notificationMessageHandler(c, &env.Metadata, env.Payload)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p.Payload.(Notification)
+}
+
+func TestLazyDecoding(t *testing.T) {
+	c := NewClient(websocketTwitch, WithLazyDecoding(), WithOnNotification(func(*Metadata, *Payload) {}))
+	n := handleTestNotification(t, c)
+
+	if _, ok := n.Event.(json.RawMessage); !ok {
+		t.Fatalf("expected raw event, got %T", n.Event)
+	}
+
+	first, err := n.Decode()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, ok := first.(*eventsub.ChannelChatMessage)
+
+	if !ok || event.Message.Text != "message 0" {
+		t.Fatalf("unexpected decoded event: %#v", first)
+	}
+
+	copied := n
+	second, err := copied.Decode()
+
+	if err != nil || second != first {
+		t.Errorf("expected decoded event to be shared by notification copies, got %p and %p (%v)", first, second, err)
+	}
+}
+
+func TestLazyDecodingTypedHandlers(t *testing.T) {
+	c := NewClient(websocketTwitch, WithLazyDecoding())
+	On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelChatMessage) {})
+	n := handleTestNotification(t, c)
+
+	if _, ok := n.Event.(*eventsub.ChannelChatMessage); !ok {
+		t.Fatalf("expected eagerly decoded event for handled type, got %T", n.Event)
+	}
+
+	if event, err := n.Decode(); err != nil || event != n.Event {
+		t.Errorf("expected Decode to return the decoded event, got %v (%v)", event, err)
+	}
+}
@@ -0,0 +1,78 @@
+package twitchws
+
+import "time"
+
+// Reasons reported to Metrics.Reconnect.
+const (
+	// ReconnectReasonSessionReconnect is reported when the connection is handed over on the server request.
+	ReconnectReasonSessionReconnect = "session_reconnect"
+
+	// ReconnectReasonConnectionLost is reported when the client re-dials after the connection has been lost.
+	ReconnectReasonConnectionLost = "connection_lost"
+)
+
+// Metrics receives measurements of the client activity. Implementations must be safe for concurrent use and should
+// return quickly as they are invoked from the message read loop. Embed NoopMetrics to implement only a subset of
+// the methods.
+type Metrics interface {
+	// MessageReceived is called for every message read from the connection, the subscription type is empty
+	// for messages that are not related to a subscription.
+	MessageReceived(messageType, subscriptionType string)
+
+	// DecodeFailed is called when a message cannot be unmarshalled.
+	DecodeFailed(messageType, subscriptionType string)
+
+	// UnsupportedEvent is called when a notification is dropped because its subscription type or version
+	// is not supported.
+	UnsupportedEvent(subscriptionType, version string)
+
+	// DuplicateSuppressed is called when a redelivered message is dropped.
+	DuplicateSuppressed(messageType string)
+
+	// Reconnect is called every time the client reconnects, see ReconnectReasonSessionReconnect and
+	// ReconnectReasonConnectionLost for the possible reasons.
+	Reconnect(reason string)
+
+	// KeepaliveTimeout is called when no keepalive or event message has been received in time.
+	KeepaliveTimeout()
+
+	// NotificationLatency reports the time passed between the notification message timestamp and its receipt.
+	NotificationLatency(subscriptionType string, latency time.Duration)
+
+	// DispatchQueueDepth reports the number of messages waiting in the dispatcher worker queue.
+	DispatchQueueDepth(worker, depth int)
+
+	// DispatchDropped is called when the dispatcher drops a message according to the overflow policy,
+	// the policy name is reported as a reason.
+	DispatchDropped(policy string)
+}
+
+// NoopMetrics is a Metrics implementation that discards all measurements.
+type NoopMetrics struct{}
+
+// MessageReceived implements Metrics.
+func (NoopMetrics) MessageReceived(string, string) {}
+
+// DecodeFailed implements Metrics.
+func (NoopMetrics) DecodeFailed(string, string) {}
+
+// UnsupportedEvent implements Metrics.
+func (NoopMetrics) UnsupportedEvent(string, string) {}
+
+// DuplicateSuppressed implements Metrics.
+func (NoopMetrics) DuplicateSuppressed(string) {}
+
+// Reconnect implements Metrics.
+func (NoopMetrics) Reconnect(string) {}
+
+// KeepaliveTimeout implements Metrics.
+func (NoopMetrics) KeepaliveTimeout() {}
+
+// NotificationLatency implements Metrics.
+func (NoopMetrics) NotificationLatency(string, time.Duration) {}
+
+// DispatchQueueDepth implements Metrics.
+func (NoopMetrics) DispatchQueueDepth(int, int) {}
+
+// DispatchDropped implements Metrics.
+func (NoopMetrics) DispatchDropped(string) {}
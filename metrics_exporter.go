@@ -0,0 +1,322 @@
+package twitchws
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLatencyBuckets defines the upper bounds in seconds of the notification latency histogram buckets.
+var defaultLatencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labelSeparator joins label values into a single map key.
+const labelSeparator = "\x00"
+
+// labelValueEscaper escapes label values according to the Prometheus text exposition format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricVec is a set of counters or gauges partitioned by label values.
+type metricVec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	values map[string]uint64
+}
+
+// histogram accumulates observations into cumulative buckets.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// MetricsExporter is a Metrics implementation that aggregates measurements in memory and exposes them
+// in the Prometheus text exposition format via ServeHTTP and as an expvar variable via Publish.
+type MetricsExporter struct {
+	// mu guards all the aggregated values.
+	mu sync.Mutex
+
+	messages       metricVec
+	decodeFailures metricVec
+	unsupported    metricVec
+	duplicates     metricVec
+	reconnects     metricVec
+	keepalives     metricVec
+	dropped        metricVec
+	queueDepth     metricVec
+
+	// buckets defines the upper bounds in seconds of the latency histogram buckets.
+	buckets []float64
+
+	// latency keeps a notification latency histogram per subscription type.
+	latency map[string]*histogram
+}
+
+// NewMetricsExporter creates a new MetricsExporter with no measurements.
+func NewMetricsExporter() *MetricsExporter {
+	return &MetricsExporter{
+		messages: newCounterVec("twitchws_messages_total",
+			"Number of messages received.", "message_type", "subscription_type"),
+		decodeFailures: newCounterVec("twitchws_decode_failures_total",
+			"Number of messages that failed to unmarshal.", "message_type", "subscription_type"),
+		unsupported: newCounterVec("twitchws_unsupported_events_total",
+			"Number of notifications dropped due to unsupported subscription type or version.",
+			"subscription_type", "version"),
+		duplicates: newCounterVec("twitchws_duplicates_suppressed_total",
+			"Number of redelivered messages dropped.", "message_type"),
+		reconnects: newCounterVec("twitchws_reconnects_total",
+			"Number of reconnects.", "reason"),
+		keepalives: newCounterVec("twitchws_keepalive_timeouts_total",
+			"Number of connections dropped due to missing keepalive messages."),
+		dropped: newCounterVec("twitchws_dispatch_dropped_total",
+			"Number of messages dropped by the dispatcher due to a full queue.", "policy"),
+		queueDepth: newGaugeVec("twitchws_dispatch_queue_depth",
+			"Number of messages waiting in the dispatcher worker queue.", "worker"),
+		buckets: defaultLatencyBuckets,
+		latency: make(map[string]*histogram),
+	}
+}
+
+// newCounterVec creates an empty counter family with the specified name, description and label names.
+func newCounterVec(name, help string, labels ...string) metricVec {
+	return metricVec{name: name, help: help, kind: "counter", labels: labels, values: make(map[string]uint64)}
+}
+
+// newGaugeVec creates an empty gauge family with the specified name, description and label names.
+func newGaugeVec(name, help string, labels ...string) metricVec {
+	return metricVec{name: name, help: help, kind: "gauge", labels: labels, values: make(map[string]uint64)}
+}
+
+// MessageReceived implements Metrics.
+func (e *MetricsExporter) MessageReceived(messageType, subscriptionType string) {
+	e.inc(&e.messages, messageType, subscriptionType)
+}
+
+// DecodeFailed implements Metrics.
+func (e *MetricsExporter) DecodeFailed(messageType, subscriptionType string) {
+	e.inc(&e.decodeFailures, messageType, subscriptionType)
+}
+
+// UnsupportedEvent implements Metrics.
+func (e *MetricsExporter) UnsupportedEvent(subscriptionType, version string) {
+	e.inc(&e.unsupported, subscriptionType, version)
+}
+
+// DuplicateSuppressed implements Metrics.
+func (e *MetricsExporter) DuplicateSuppressed(messageType string) {
+	e.inc(&e.duplicates, messageType)
+}
+
+// Reconnect implements Metrics.
+func (e *MetricsExporter) Reconnect(reason string) {
+	e.inc(&e.reconnects, reason)
+}
+
+// KeepaliveTimeout implements Metrics.
+func (e *MetricsExporter) KeepaliveTimeout() {
+	e.inc(&e.keepalives)
+}
+
+// DispatchQueueDepth implements Metrics.
+func (e *MetricsExporter) DispatchQueueDepth(worker, depth int) {
+	e.mu.Lock()
+	e.queueDepth.values[strconv.Itoa(worker)] = uint64(max(depth, 0))
+	e.mu.Unlock()
+}
+
+// DispatchDropped implements Metrics.
+func (e *MetricsExporter) DispatchDropped(policy string) {
+	e.inc(&e.dropped, policy)
+}
+
+// NotificationLatency implements Metrics.
+func (e *MetricsExporter) NotificationLatency(subscriptionType string, latency time.Duration) {
+	seconds := latency.Seconds()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	h, ok := e.latency[subscriptionType]
+
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(e.buckets))}
+		e.latency[subscriptionType] = h
+	}
+
+	for i, upper := range e.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += seconds
+	h.count++
+}
+
+// inc increments the counter with the specified label values.
+func (e *MetricsExporter) inc(v *metricVec, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+
+	e.mu.Lock()
+	v.values[key]++
+	e.mu.Unlock()
+}
+
+// ServeHTTP writes the aggregated measurements in the Prometheus text exposition format.
+func (e *MetricsExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = e.WriteTo(w)
+}
+
+// WriteTo writes the aggregated measurements in the Prometheus text exposition format to w.
+func (e *MetricsExporter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	e.mu.Lock()
+	for _, v := range e.metricVecs() {
+		writeMetricVec(cw, v)
+	}
+
+	e.writeLatency(cw)
+	e.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+
+	return cw.n, cw.w.Flush()
+}
+
+// Publish exposes the aggregated measurements as an expvar variable with the specified name.
+// Like expvar.Publish, it panics if the name is already registered.
+func (e *MetricsExporter) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return e.snapshot()
+	}))
+}
+
+// metricVecs returns all the counter and gauge families in the exposition order.
+func (e *MetricsExporter) metricVecs() []*metricVec {
+	return []*metricVec{
+		&e.messages, &e.decodeFailures, &e.unsupported, &e.duplicates, &e.reconnects, &e.keepalives,
+		&e.dropped, &e.queueDepth,
+	}
+}
+
+// snapshot returns a copy of the aggregated measurements keyed by the metric name and the formatted label set.
+func (e *MetricsExporter) snapshot() map[string]map[string]any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make(map[string]map[string]any)
+
+	for _, v := range e.metricVecs() {
+		values := make(map[string]any, len(v.values))
+
+		for key, value := range v.values {
+			values[formatLabels(v.labels, key)] = value
+		}
+
+		out[v.name] = values
+	}
+
+	latency := make(map[string]any, len(e.latency))
+
+	for subscriptionType, h := range e.latency {
+		latency[formatLabels([]string{"subscription_type"}, subscriptionType)] = map[string]any{
+			"count": h.count,
+			"sum":   h.sum,
+		}
+	}
+
+	out["twitchws_notification_latency_seconds"] = latency
+
+	return out
+}
+
+// writeLatency writes the notification latency histograms.
+func (e *MetricsExporter) writeLatency(w *countingWriter) {
+	const name = "twitchws_notification_latency_seconds"
+	w.printf("# HELP %s Time passed between the notification timestamp and its receipt.\n", name)
+	w.printf("# TYPE %s histogram\n", name)
+
+	for _, subscriptionType := range sortedKeys(e.latency) {
+		h := e.latency[subscriptionType]
+		labels := formatLabels([]string{"subscription_type"}, subscriptionType)
+
+		for i, upper := range e.buckets {
+			le := strconv.FormatFloat(upper, 'g', -1, 64)
+			w.printf("%s_bucket{%s,le=\"%s\"} %d\n", name, labels, le, h.counts[i])
+		}
+
+		w.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		w.printf("%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		w.printf("%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+// writeMetricVec writes the metric family in the Prometheus text exposition format.
+func writeMetricVec(w *countingWriter, v *metricVec) {
+	w.printf("# HELP %s %s\n", v.name, v.help)
+	w.printf("# TYPE %s %s\n", v.name, v.kind)
+
+	if len(v.labels) == 0 {
+		w.printf("%s %d\n", v.name, v.values[""])
+		return
+	}
+
+	for _, key := range sortedKeys(v.values) {
+		w.printf("%s{%s} %d\n", v.name, formatLabels(v.labels, key), v.values[key])
+	}
+}
+
+// formatLabels formats the label names and the joined label values as a Prometheus label set.
+func formatLabels(names []string, key string) string {
+	values := strings.Split(key, labelSeparator)
+	pairs := make([]string, len(names))
+
+	for i, name := range names {
+		pairs[i] = name + `="` + labelValueEscaper.Replace(values[i]) + `"`
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// sortedKeys returns the map keys in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// countingWriter writes formatted output while keeping track of the written bytes and the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// printf writes the formatted output unless a previous write failed.
+func (w *countingWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
@@ -0,0 +1,85 @@
+package twitchws
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExporter(t *testing.T) {
+	e := NewMetricsExporter()
+	e.MessageReceived("notification", "channel.follow")
+	e.MessageReceived("notification", "channel.follow")
+	e.MessageReceived("session_keepalive", "")
+	e.DecodeFailed("notification", "channel.ban")
+	e.UnsupportedEvent("channel.chat.notification", "1")
+	e.DuplicateSuppressed("notification")
+	e.Reconnect(ReconnectReasonSessionReconnect)
+	e.KeepaliveTimeout()
+	e.NotificationLatency("channel.follow", 30*time.Millisecond)
+	e.NotificationLatency("channel.follow", 2*time.Second)
+	e.DispatchQueueDepth(0, 5)
+	e.DispatchQueueDepth(0, 3)
+	e.DispatchDropped(OverflowDropOldest.String())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	expected := []string{
+		"# TYPE twitchws_messages_total counter",
+		`twitchws_messages_total{message_type="notification",subscription_type="channel.follow"} 2`,
+		`twitchws_messages_total{message_type="session_keepalive",subscription_type=""} 1`,
+		`twitchws_decode_failures_total{message_type="notification",subscription_type="channel.ban"} 1`,
+		`twitchws_unsupported_events_total{subscription_type="channel.chat.notification",version="1"} 1`,
+		`twitchws_duplicates_suppressed_total{message_type="notification"} 1`,
+		`twitchws_reconnects_total{reason="session_reconnect"} 1`,
+		"twitchws_keepalive_timeouts_total 1",
+		`twitchws_dispatch_dropped_total{policy="drop_oldest"} 1`,
+		"# TYPE twitchws_dispatch_queue_depth gauge",
+		`twitchws_dispatch_queue_depth{worker="0"} 3`,
+		"# TYPE twitchws_notification_latency_seconds histogram",
+		`twitchws_notification_latency_seconds_bucket{subscription_type="channel.follow",le="0.025"} 0`,
+		`twitchws_notification_latency_seconds_bucket{subscription_type="channel.follow",le="0.05"} 1`,
+		`twitchws_notification_latency_seconds_bucket{subscription_type="channel.follow",le="2.5"} 2`,
+		`twitchws_notification_latency_seconds_bucket{subscription_type="channel.follow",le="+Inf"} 2`,
+		`twitchws_notification_latency_seconds_sum{subscription_type="channel.follow"} 2.03`,
+		`twitchws_notification_latency_seconds_count{subscription_type="channel.follow"} 2`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsExporterLabelEscaping(t *testing.T) {
+	e := NewMetricsExporter()
+	e.Reconnect("quote\" and \\ and\nnewline")
+
+	var sb strings.Builder
+
+	if _, err := e.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `twitchws_reconnects_total{reason="quote\" and \\ and\nnewline"} 1`
+
+	if !strings.Contains(sb.String(), expected) {
+		t.Errorf("expected escaped label %q in output:\n%s", expected, sb.String())
+	}
+}
+
+func TestMetricsExporterSnapshot(t *testing.T) {
+	e := NewMetricsExporter()
+	e.MessageReceived("notification", "channel.follow")
+
+	snapshot := e.snapshot()
+	value := snapshot["twitchws_messages_total"][`message_type="notification",subscription_type="channel.follow"`]
+
+	if value != uint64(1) {
+		t.Errorf("expected 1 message in snapshot, got %v", value)
+	}
+}
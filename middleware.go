@@ -0,0 +1,178 @@
+package twitchws
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// Handler is a message callback wrapped by middlewares.
+type Handler = OnMessageEventFn
+
+// Middleware wraps a Handler to run code before and after it, or to skip it altogether.
+type Middleware func(next Handler) Handler
+
+// PanicError wraps a value recovered from a panicking callback. It is reported to the OnError callback
+// if the recovery is enabled with WithRecovery.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the moment of the panic.
+	Stack []byte
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("callback panicked: %v", e.Value)
+}
+
+// chain wraps the handler into the middlewares, the first middleware being the outermost one.
+func chain(h Handler, middlewares []Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// wrap wraps the message callback into the client middlewares and, if enabled, the recovery, which is
+// the outermost one. Returns nil for a nil callback.
+func (c *Client) wrap(h Handler) Handler {
+	if h == nil {
+		return nil
+	}
+
+	h = chain(h, c.middlewares)
+
+	if !c.recovery {
+		return h
+	}
+
+	return func(m *Metadata, p *Payload) {
+		defer c.recoverPanic(m)
+
+		h(m, p)
+	}
+}
+
+// wrapCallbacks wraps the message callbacks into the middlewares once the options are applied and, if enabled,
+// the lifecycle callbacks into the recovery.
+func (c *Client) wrapCallbacks() {
+	c.onWelcomeMessage = c.wrap(c.onWelcomeMessage)
+	c.onKeepaliveMessage = c.wrap(c.onKeepaliveMessage)
+	c.onNotificationMessage = c.wrap(c.onNotificationMessage)
+	c.onRawNotificationMessage = c.wrap(c.onRawNotificationMessage)
+	c.onRevocationMessage = c.wrap(c.onRevocationMessage)
+	c.onReconnectMessage = c.wrap(c.onReconnectMessage)
+
+	if !c.recovery {
+		return
+	}
+
+	if fn := c.onConnect; fn != nil {
+		c.onConnect = func() {
+			defer c.recoverPanic(nil)
+
+			fn()
+		}
+	}
+
+	if fn := c.onDisconnect; fn != nil {
+		c.onDisconnect = func() {
+			defer c.recoverPanic(nil)
+
+			fn()
+		}
+	}
+
+	if fn := c.onStateChange; fn != nil {
+		c.onStateChange = func(previous, current ConnectionState, err error) {
+			defer c.recoverPanic(nil)
+
+			fn(previous, current, err)
+		}
+	}
+
+	if fn := c.onClose; fn != nil {
+		c.onClose = func(reason CloseReason) {
+			defer c.recoverPanic(nil)
+
+			fn(reason)
+		}
+	}
+
+	if fn := c.onDuplicate; fn != nil {
+		c.onDuplicate = func(m *Metadata) {
+			defer c.recoverPanic(m)
+
+			fn(m)
+		}
+	}
+
+	if fn := c.onError; fn != nil {
+		c.onError = func(e ErrorEvent) {
+			// a panic in the OnError callback cannot be reported to itself, so it is logged instead
+			defer func() {
+				if r := recover(); r != nil {
+					c.logger.Error("OnError callback panicked", "panic", r, "stack", string(debug.Stack()))
+				}
+			}()
+
+			fn(e)
+		}
+	}
+}
+
+// recoverPanic recovers from a panicking callback and reports it to the OnError callback as PanicError.
+// Must be deferred directly.
+func (c *Client) recoverPanic(m *Metadata) {
+	if r := recover(); r != nil {
+		c.reportError(&PanicError{Value: r, Stack: debug.Stack()}, m, nil)
+	}
+}
+
+// Timing returns a middleware that passes the time spent in the wrapped handler to observe.
+func Timing(observe func(m *Metadata, d time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(m *Metadata, p *Payload) {
+			start := time.Now()
+
+			defer func() {
+				observe(m, time.Since(start))
+			}()
+
+			next(m, p)
+		}
+	}
+}
+
+// Logging returns a middleware that logs every message passed to the wrapped handler along with the time spent
+// handling it at debug level.
+func Logging(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(m *Metadata, p *Payload) {
+			start := time.Now()
+
+			next(m, p)
+
+			logger.Debug("Message handled",
+				slog.String("message_id", m.MessageID),
+				slog.String("message_type", m.MessageType),
+				slog.String("subscription_type", m.SubscriptionType),
+				slog.Duration("duration", time.Since(start)))
+		}
+	}
+}
+
+// Filter returns a middleware that invokes the wrapped handler only for the messages matching the predicate.
+func Filter(predicate func(m *Metadata, p *Payload) bool) Middleware {
+	return func(next Handler) Handler {
+		return func(m *Metadata, p *Payload) {
+			if predicate(m, p) {
+				next(m, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package twitchws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(m *Metadata, p *Payload) {
+				calls = append(calls, name+" before")
+				next(m, p)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+	c := NewClient(websocketTwitch, WithMiddleware(trace("first"), trace("second")), WithOnKeepalive(func(*Metadata, *Payload) {
+		calls = append(calls, "handler")
+	}))
+
+	c.dispatch(c.onKeepaliveMessage, &Metadata{}, &Payload{})
+
+	expected := []string{"first before", "second before", "handler", "second after", "first after"}
+
+	if !slices.Equal(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestMiddlewarePerHandler(t *testing.T) {
+	var (
+		observed int
+		handled  []string
+	)
+
+	c := NewClient(websocketTwitch, WithMiddleware(Timing(func(*Metadata, time.Duration) {
+		observed++
+	})))
+
+	for _, name := range []string{"first", "second"} {
+		On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelChatMessage) {
+			handled = append(handled, name)
+		})
+	}
+
+	n, err := unmarshalTestNotification(chatMessageNotification(0), registryChain{DefaultRegistry})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.handleNotification(&Metadata{MessageType: "notification"}, &Payload{Payload: n})
+
+	if !slices.Equal(handled, []string{"first", "second"}) || observed != len(handled) {
+		t.Errorf("expected every handler to be timed separately, got %d observations for %v", observed, handled)
+	}
+}
+
+func TestWithRecovery(t *testing.T) {
+	var (
+		reported []ErrorEvent
+		handled  int
+	)
+
+	c := NewClient(websocketTwitch,
+		WithRecovery(),
+		WithOnError(func(e ErrorEvent) {
+			reported = append(reported, e)
+		}),
+		WithOnConnect(func() {
+			panic("connect")
+		}),
+		WithOnStateChange(func(ConnectionState, ConnectionState, error) {
+			panic("state change")
+		}),
+	)
+	On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelChatMessage) {
+		panic("handler")
+	})
+	On(c, func(context.Context, *Metadata, EventsubSubscription, *eventsub.ChannelChatMessage) {
+		handled++
+	})
+
+	n, err := unmarshalTestNotification(chatMessageNotification(0), registryChain{DefaultRegistry})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Metadata{MessageType: "notification"}
+	c.handleNotification(m, &Payload{Payload: n})
+	c.onConnect()
+	c.setState(StateConnecting, nil)
+
+	if handled != 1 {
+		t.Errorf("expected the handler after the panicking one to be invoked once, got %d", handled)
+	}
+
+	var values []any
+
+	for _, e := range reported {
+		var panicErr *PanicError
+
+		if !errors.As(e.Err, &panicErr) || len(panicErr.Stack) == 0 {
+			t.Fatalf("expected a PanicError, got %v", e.Err)
+		}
+
+		values = append(values, panicErr.Value)
+	}
+
+	if !slices.Equal(values, []any{"handler", "connect", "state change"}) || reported[0].Metadata != m {
+		t.Errorf("unexpected reported panics: %v", values)
+	}
+}
+
+func TestWithRecoveryPanickingOnError(t *testing.T) {
+	c := NewClient(websocketTwitch,
+		WithRecovery(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithOnError(func(ErrorEvent) {
+			panic("error")
+		}),
+		WithOnDisconnect(func() {
+			panic("disconnect")
+		}),
+	)
+
+	// both panics are recovered, the one of the OnError callback is logged
+	c.onDisconnect()
+}
+
+func TestFilterAndTiming(t *testing.T) {
+	var (
+		handled  []string
+		observed []string
+	)
+
+	h := chain(func(m *Metadata, _ *Payload) {
+		handled = append(handled, m.MessageType)
+	}, []Middleware{
+		Timing(func(m *Metadata, d time.Duration) {
+			if d < 0 {
+				t.Errorf("negative duration: %v", d)
+			}
+
+			observed = append(observed, m.MessageType)
+		}),
+		Filter(func(m *Metadata, _ *Payload) bool {
+			return m.MessageType == "notification"
+		}),
+	})
+
+	for _, messageType := range []string{"session_keepalive", "notification"} {
+		h(&Metadata{MessageType: messageType}, &Payload{})
+	}
+
+	if !slices.Equal(handled, []string{"notification"}) {
+		t.Errorf("unexpected handled messages: %v", handled)
+	}
+
+	if !slices.Equal(observed, []string{"session_keepalive", "notification"}) {
+		t.Errorf("unexpected observed messages: %v", observed)
+	}
+}
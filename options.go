@@ -1,5 +1,10 @@
 package twitchws
 
+import (
+	"log/slog"
+	"time"
+)
+
 // Option is a functional option used to configure a Client instance.
 type Option func(*Client)
 
@@ -24,6 +29,15 @@ func WithOnNotification(fn OnMessageEventFn) Option {
 	}
 }
 
+// WithOnRawNotification sets the callback function to handle notifications of subscription types or versions
+// the client has no event struct for. The payload holds a RawNotification with the event JSON as received.
+// Without the callback such notifications are reported as ErrUnsupportedEvent.
+func WithOnRawNotification(fn OnMessageEventFn) Option {
+	return func(c *Client) {
+		c.onRawNotificationMessage = fn
+	}
+}
+
 // WithOnRevocation sets a callback function to handle revocation messages and returns an Option for the Client configuration.
 func WithOnRevocation(fn OnMessageEventFn) Option {
 	return func(c *Client) {
@@ -51,3 +65,147 @@ func WithOnDisconnect(fn OnEventFn) Option {
 		c.onDisconnect = fn
 	}
 }
+
+// WithOnStateChange sets the callback function to be executed on every connection state transition.
+// The error is the one that caused the transition, if any.
+func WithOnStateChange(fn OnStateChangeFn) Option {
+	return func(c *Client) {
+		c.onStateChange = fn
+	}
+}
+
+// WithOnClose sets the callback function to be executed when the server closes the connection with a close frame,
+// e.g. with one of the Twitch specific close codes.
+func WithOnClose(fn OnCloseFn) Option {
+	return func(c *Client) {
+		c.onClose = fn
+	}
+}
+
+// WithOnDuplicate sets the callback function to be executed when a redelivered message is suppressed.
+func WithOnDuplicate(fn OnDuplicateFn) Option {
+	return func(c *Client) {
+		c.onDuplicate = fn
+	}
+}
+
+// WithOnError sets the callback function to be executed when a received message cannot be handled, e.g. because
+// of an unsupported subscription type or a decoding failure.
+func WithOnError(fn OnErrorFn) Option {
+	return func(c *Client) {
+		c.onError = fn
+	}
+}
+
+// WithDedupWindow sets how long received message IDs are tracked to suppress redelivered messages.
+// The default window is 10 minutes.
+func WithDedupWindow(window time.Duration) Option {
+	return func(c *Client) {
+		c.dedupWindow = window
+	}
+}
+
+// WithDedupStore sets the store used to track received message IDs, e.g. a FileDedupStore shared by several
+// client instances. An in-process MemoryDedupStore is used by default.
+func WithDedupStore(store DedupStore) Option {
+	return func(c *Client) {
+		c.msgTracking = store
+	}
+}
+
+// WithReconnectPolicy sets the policy that decides whether and when the client reconnects after a failed dial
+// or a dropped session. Without a policy the client stops on the first failed dial and re-dials a dropped session
+// immediately.
+func WithReconnectPolicy(policy ReconnectPolicy) Option {
+	return func(c *Client) {
+		c.reconnectPolicy = policy
+	}
+}
+
+// WithReconnectWelcomeTimeout sets how long the client waits for the welcome message on the connection to the URL
+// received with a session_reconnect message. The default timeout is 1 minute.
+func WithReconnectWelcomeTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.reconnectWelcomeTimeout = timeout
+	}
+}
+
+// WithLogger sets the logger the client reports its activity to. slog.Default is used if the logger is nil
+// or the option is not specified.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithMetrics sets the receiver of the client activity measurements, e.g. a MetricsExporter.
+// Measurements are discarded if the metrics are nil or the option is not specified.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Client) {
+		if metrics != nil {
+			c.metrics = metrics
+		}
+	}
+}
+
+// WithDispatcher enables asynchronous dispatch of the message callbacks to a bounded pool of workers, so slow
+// callbacks do not block reading of the connection. Messages with the same key keep their order. Connection
+// callbacks such as OnConnect and OnDisconnect are still invoked inline.
+func WithDispatcher(config DispatcherConfig) Option {
+	return func(c *Client) {
+		c.dispatcherConfig = &config
+	}
+}
+
+// WithEventStream enables delivery of notifications and revocations to the Events channel and connection state
+// transitions to the Lifecycle channel, both created with the specified buffer size. Callbacks are still invoked
+// when the event stream is enabled.
+func WithEventStream(buffer int) Option {
+	return func(c *Client) {
+		c.streamState = &eventStreamState{buffer: max(buffer, 0)}
+	}
+}
+
+// WithMiddleware appends middlewares wrapping every message callback and every handler registered with On
+// separately. The first middleware is the outermost one.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithRecovery recovers panics in every callback, including the handlers registered with On and the lifecycle
+// callbacks, and reports them to the OnError callback as PanicError, so a faulty callback does not stop the client.
+// The recovery wraps the middlewares set with WithMiddleware.
+func WithRecovery() Option {
+	return func(c *Client) {
+		c.recovery = true
+	}
+}
+
+// WithRegistry sets a registry of event structs looked up before DefaultRegistry, so the client can decode
+// notifications into its own structs without affecting other clients.
+func WithRegistry(r *Registry) Option {
+	return func(c *Client) {
+		c.registry = r
+	}
+}
+
+// WithLazyDecoding keeps notification events as json.RawMessage until Notification.Decode is called, so events
+// of subscription types nobody inspects are never decoded. Events of subscription types with handlers registered
+// with On are still decoded eagerly.
+func WithLazyDecoding() Option {
+	return func(c *Client) {
+		c.lazyDecoding = true
+	}
+}
+
+// WithRecorder sets the recorder every frame read from the connection is passed to along with its receipt time,
+// connection generation and session ID, e.g. a Recorder writing them to rotating archives.
+func WithRecorder(recorder FrameRecorder) Option {
+	return func(c *Client) {
+		c.recorder = recorder
+	}
+}
@@ -0,0 +1,164 @@
+package twitchwstest
+
+import (
+	"fmt"
+	"slices"
+)
+
+// conditionFields lists the condition fields of a subscription type.
+type conditionFields struct {
+	// required are the fields every subscription of the type has to set.
+	required []string
+
+	// optional are the fields a subscription of the type may set.
+	optional []string
+
+	// routing are the fields the events of the type are routed by, the other ones only name the user who authorized
+	// the subscription. Every field routes the events if none is listed.
+	routing []string
+}
+
+// Condition fields shared by many subscription types.
+var (
+	broadcasterCondition     = conditionFields{required: []string{"broadcaster_user_id"}}
+	broadcasterUserCondition = conditionFields{required: []string{"broadcaster_user_id", "user_id"}}
+	clientCondition          = conditionFields{required: []string{"client_id"}}
+	userCondition            = conditionFields{required: []string{"user_id"}}
+	rewardCondition          = conditionFields{
+		required: []string{"broadcaster_user_id"},
+		optional: []string{"reward_id"},
+	}
+
+	// broadcasterModeratorCondition routes the events by the broadcaster only: the events name the acting moderator
+	// with the same field, who need not be the moderator reading them.
+	broadcasterModeratorCondition = conditionFields{
+		required: []string{"broadcaster_user_id", "moderator_user_id"},
+		routing:  []string{"broadcaster_user_id"},
+	}
+)
+
+// Condition fields specific to a single subscription type.
+var (
+	conduitCondition   = conditionFields{required: []string{"client_id"}, optional: []string{"conduit_id"}}
+	extensionCondition = conditionFields{required: []string{"extension_client_id"}}
+	dropCondition      = conditionFields{
+		required: []string{"organization_id"},
+		optional: []string{"category_id", "campaign_id"},
+	}
+
+	// raidCondition sets either of the fields to follow the outgoing or the incoming raids.
+	raidCondition = conditionFields{optional: []string{"from_broadcaster_user_id", "to_broadcaster_user_id"}}
+)
+
+// subscriptionConditions maps the subscription types documented by Twitch to their condition fields, the same for
+// every version of a type.
+var subscriptionConditions = map[string]conditionFields{
+	"automod.message.hold":    broadcasterModeratorCondition,
+	"automod.message.update":  broadcasterModeratorCondition,
+	"automod.settings.update": broadcasterModeratorCondition,
+	"automod.terms.update":    broadcasterModeratorCondition,
+	"channel.ad_break.begin":  broadcasterCondition,
+	"channel.ban":             broadcasterCondition,
+	"channel.bits.use":        broadcasterCondition,
+	"channel.channel_points_automatic_reward_redemption.add": broadcasterCondition,
+	"channel.channel_points_custom_reward.add":               broadcasterCondition,
+	"channel.channel_points_custom_reward.remove":            rewardCondition,
+	"channel.channel_points_custom_reward.update":            rewardCondition,
+	"channel.channel_points_custom_reward_redemption.add":    rewardCondition,
+	"channel.channel_points_custom_reward_redemption.update": rewardCondition,
+	"channel.charity_campaign.donate":                        broadcasterCondition,
+	"channel.charity_campaign.progress":                      broadcasterCondition,
+	"channel.charity_campaign.start":                         broadcasterCondition,
+	"channel.charity_campaign.stop":                          broadcasterCondition,
+	"channel.chat.clear":                                     broadcasterUserCondition,
+	"channel.chat.clear_user_messages":                       broadcasterUserCondition,
+	"channel.chat.message":                                   broadcasterUserCondition,
+	"channel.chat.message_delete":                            broadcasterUserCondition,
+	"channel.chat.notification":                              broadcasterUserCondition,
+	"channel.chat.user_message_hold":                         broadcasterUserCondition,
+	"channel.chat.user_message_update":                       broadcasterUserCondition,
+	"channel.chat_settings.update":                           broadcasterUserCondition,
+	"channel.cheer":                                          broadcasterCondition,
+	"channel.follow":                                         broadcasterModeratorCondition,
+	"channel.goal.begin":                                     broadcasterCondition,
+	"channel.goal.end":                                       broadcasterCondition,
+	"channel.goal.progress":                                  broadcasterCondition,
+	"channel.guest_star_guest.update":                        broadcasterModeratorCondition,
+	"channel.guest_star_session.begin":                       broadcasterModeratorCondition,
+	"channel.guest_star_session.end":                         broadcasterModeratorCondition,
+	"channel.guest_star_settings.update":                     broadcasterModeratorCondition,
+	"channel.hype_train.begin":                               broadcasterCondition,
+	"channel.hype_train.end":                                 broadcasterCondition,
+	"channel.hype_train.progress":                            broadcasterCondition,
+	"channel.moderate":                                       broadcasterModeratorCondition,
+	"channel.moderator.add":                                  broadcasterCondition,
+	"channel.moderator.remove":                               broadcasterCondition,
+	"channel.poll.begin":                                     broadcasterCondition,
+	"channel.poll.end":                                       broadcasterCondition,
+	"channel.poll.progress":                                  broadcasterCondition,
+	"channel.prediction.begin":                               broadcasterCondition,
+	"channel.prediction.end":                                 broadcasterCondition,
+	"channel.prediction.lock":                                broadcasterCondition,
+	"channel.prediction.progress":                            broadcasterCondition,
+	"channel.raid":                                           raidCondition,
+	"channel.shared_chat.begin":                              broadcasterCondition,
+	"channel.shared_chat.end":                                broadcasterCondition,
+	"channel.shared_chat.update":                             broadcasterCondition,
+	"channel.shield_mode.begin":                              broadcasterModeratorCondition,
+	"channel.shield_mode.end":                                broadcasterModeratorCondition,
+	"channel.shoutout.create":                                broadcasterModeratorCondition,
+	"channel.shoutout.receive":                               broadcasterModeratorCondition,
+	"channel.subscribe":                                      broadcasterCondition,
+	"channel.subscription.end":                               broadcasterCondition,
+	"channel.subscription.gift":                              broadcasterCondition,
+	"channel.subscription.message":                           broadcasterCondition,
+	"channel.suspicious_user.message":                        broadcasterModeratorCondition,
+	"channel.suspicious_user.update":                         broadcasterModeratorCondition,
+	"channel.unban":                                          broadcasterCondition,
+	"channel.unban_request.create":                           broadcasterModeratorCondition,
+	"channel.unban_request.resolve":                          broadcasterModeratorCondition,
+	"channel.update":                                         broadcasterCondition,
+	"channel.vip.add":                                        broadcasterCondition,
+	"channel.vip.remove":                                     broadcasterCondition,
+	"channel.warning.acknowledge":                            broadcasterModeratorCondition,
+	"channel.warning.send":                                   broadcasterModeratorCondition,
+	"conduit.shard.disabled":                                 conduitCondition,
+	"drop.entitlement.grant":                                 dropCondition,
+	"extension.bits_transaction.create":                      extensionCondition,
+	"stream.offline":                                         broadcasterCondition,
+	"stream.online":                                          broadcasterCondition,
+	"user.authorization.grant":                               clientCondition,
+	"user.authorization.revoke":                              clientCondition,
+	"user.update":                                            userCondition,
+	"user.whisper.message":                                   userCondition,
+}
+
+// validateCondition checks the condition fields against the ones of the subscription type: every required field
+// has to be set and no unknown field is accepted. The condition of a subscription type unknown to the server
+// is not checked.
+func validateCondition(subType string, condition map[string]string) error {
+	fields, ok := subscriptionConditions[subType]
+
+	if !ok {
+		return nil
+	}
+
+	for _, key := range fields.required {
+		if _, ok = condition[key]; !ok {
+			return fmt.Errorf("missing condition field %s for %s", key, subType)
+		}
+	}
+
+	for key := range condition {
+		if !slices.Contains(fields.required, key) && !slices.Contains(fields.optional, key) {
+			return fmt.Errorf("unsupported condition field %s for %s", key, subType)
+		}
+	}
+
+	return nil
+}
+
+// routes reports whether the events of the subscription type are routed by the condition field.
+func (f conditionFields) routes(key string) bool {
+	return len(f.routing) == 0 || slices.Contains(f.routing, key)
+}
@@ -0,0 +1,93 @@
+package twitchwstest
+
+import (
+	"net"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// malformedFrame is a notification message cut off in the middle.
+const malformedFrame = `{"metadata":{"message_id":"0","message_type":"notification","message_timestamp":"2024-01-01T00:00:00Z"},` +
+	`"payload":{"subscription":{"type":`
+
+// PauseKeepalive suppresses the session_keepalive messages until ResumeKeepalive is called, so the client keepalive
+// timeout expires unless other messages are sent.
+func (s *Session) PauseKeepalive() {
+	s.keepalivePaused.Store(true)
+}
+
+// ResumeKeepalive resumes the session_keepalive messages suppressed by PauseKeepalive.
+func (s *Session) ResumeKeepalive() {
+	s.keepalivePaused.Store(false)
+}
+
+// DelayMessages holds every subsequent message back for the specified time before it is sent, keepalive messages
+// included. A zero delay sends the messages right away again.
+func (s *Session) DelayMessages(delay time.Duration) {
+	s.mu.Lock()
+	s.delay = delay
+	s.mu.Unlock()
+}
+
+// ShiftTimestamps shifts the timestamps generated for the subsequent messages by the offset. A negative offset makes
+// the following messages appear to be sent before the already sent ones.
+func (s *Session) ShiftTimestamps(offset time.Duration) {
+	s.mu.Lock()
+	s.timestampOffset = offset
+	s.mu.Unlock()
+}
+
+// Resend sends the notification or revocation message with the ID returned by Send, Notify or Revoke again as is,
+// so the client receives a duplicate message ID. Only the latest 1024 such messages are kept. Returns
+// ErrUnknownMessage if the session has not sent a notification or revocation message with the ID or it has been
+// dropped since.
+func (s *Session) Resend(id string) error {
+	s.mu.Lock()
+	frame, ok := s.sent[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrUnknownMessage
+	}
+
+	return s.SendRaw(frame)
+}
+
+// SendBinary sends the data as a binary message, which Twitch never sends.
+func (s *Session) SendBinary(data []byte) error {
+	return s.write(websocket.MessageBinary, data)
+}
+
+// SendMalformed sends a text message that is not valid JSON.
+func (s *Session) SendMalformed() error {
+	return s.SendRaw([]byte(malformedFrame))
+}
+
+// Reset aborts the TCP connection with a reset instead of a WebSocket close frame.
+func (s *Session) Reset() {
+	if tcpConn, ok := s.netConn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+
+	if s.netConn != nil {
+		_ = s.netConn.Close()
+	} else {
+		_ = s.conn.CloseNow()
+	}
+
+	s.finish()
+}
+
+// SilentURL returns the URL of connections that are accepted but never receive a welcome message, e.g. to send
+// with session_reconnect messages in order to expire the client reconnect timeout.
+func (s *Server) SilentURL() string {
+	return s.wsURL(PathSilent)
+}
+
+// RefusingURL returns the URL of connections that are refused with the 503 Service Unavailable status instead of
+// the WebSocket handshake, e.g. to send with session_reconnect messages. Unlike a closed port, the URL keeps
+// refusing connections as long as the server is running.
+func (s *Server) RefusingURL() string {
+	return s.wsURL(PathRefused)
+}
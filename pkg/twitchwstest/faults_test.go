@@ -0,0 +1,205 @@
+package twitchwstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// newFaultSession starts a server with the options, connects to it and reads the welcome message.
+func newFaultSession(t *testing.T, ctx context.Context, opts ...Option) (*websocket.Conn, *Session) {
+	t.Helper()
+
+	srv := NewServer(opts...)
+	t.Cleanup(srv.Close)
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn, session
+}
+
+func TestSessionPauseKeepalive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx, WithKeepaliveInterval(50*time.Millisecond))
+	session.PauseKeepalive()
+	time.Sleep(200 * time.Millisecond)
+
+	if _, err := session.Notify(Subscription{Type: "channel.follow"}, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := read(t, ctx, conn); m.Metadata.MessageType != "notification" {
+		t.Errorf("expected notification while keepalive is paused, got %s", m.Metadata.MessageType)
+	}
+
+	session.ResumeKeepalive()
+
+	if m := read(t, ctx, conn); m.Metadata.MessageType != "session_keepalive" {
+		t.Errorf("expected keepalive message after resume, got %s", m.Metadata.MessageType)
+	}
+}
+
+func TestSessionDelayMessages(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx)
+	session.DelayMessages(delay)
+	start := time.Now()
+
+	go func() {
+		_ = session.Keepalive()
+	}()
+
+	read(t, ctx, conn)
+
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("expected message delayed for %v, got %v", delay, elapsed)
+	}
+}
+
+func TestSessionResendAndShiftTimestamps(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx)
+	sub := Subscription{Type: "channel.follow"}
+
+	if _, err := session.Notify(sub, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	session.ShiftTimestamps(-time.Hour)
+	id, err := session.Notify(sub, struct{}{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the resent message is the requested one, whatever has been sent in the meantime
+	if err = session.Keepalive(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.Resend(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.Resend("unknown"); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("expected %v, got %v", ErrUnknownMessage, err)
+	}
+
+	first, shifted := read(t, ctx, conn), read(t, ctx, conn)
+	read(t, ctx, conn)
+	duplicate := read(t, ctx, conn)
+
+	if shifted.Metadata.MessageTimestamp >= first.Metadata.MessageTimestamp {
+		t.Errorf("expected timestamp %s before %s", shifted.Metadata.MessageTimestamp, first.Metadata.MessageTimestamp)
+	}
+
+	if shifted.Metadata.MessageID != id || duplicate.Metadata != shifted.Metadata {
+		t.Errorf("expected duplicate of %+v, got %+v", shifted.Metadata, duplicate.Metadata)
+	}
+}
+
+func TestSessionResendLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, session := newFaultSession(t, ctx)
+	id, err := session.Send(Metadata{MessageType: "session_keepalive"}, struct{}{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.Resend(id); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("expected %v for keepalive message, got %v", ErrUnknownMessage, err)
+	}
+
+	for i := range resendLimit + 1 {
+		session.keepForResend(strconv.Itoa(i), []byte("{}"))
+	}
+
+	if _, ok := session.sent["0"]; ok || len(session.sent) != resendLimit || len(session.sentOrder) != resendLimit {
+		t.Errorf("expected the oldest of %d messages dropped, got %d kept", resendLimit+1, len(session.sent))
+	}
+}
+
+func TestSessionInvalidFrames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx)
+
+	if err := session.SendBinary([]byte{0x00, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.SendMalformed(); err != nil {
+		t.Fatal(err)
+	}
+
+	if typ, _, err := conn.Read(ctx); err != nil || typ != websocket.MessageBinary {
+		t.Errorf("expected binary message, got %v, %v", typ, err)
+	}
+
+	if typ, data, err := conn.Read(ctx); err != nil || typ != websocket.MessageText || json.Valid(data) {
+		t.Errorf("expected malformed text message, got %v, %s, %v", typ, data, err)
+	}
+}
+
+func TestSessionReset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx)
+	session.Reset()
+
+	if _, _, err := conn.Read(ctx); err == nil || websocket.CloseStatus(err) != -1 {
+		t.Errorf("expected connection error without close frame, got %v", err)
+	}
+
+	<-session.Done()
+}
+
+func TestServerFaultURLs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	if conn, resp, err := websocket.Dial(ctx, srv.RefusingURL(), nil); err == nil ||
+		resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		if conn != nil {
+			_ = conn.CloseNow()
+		}
+
+		t.Errorf("expected refused connection, got %v", err)
+	}
+
+	conn := dial(t, ctx, srv.SilentURL())
+	readCtx, readCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer readCancel()
+
+	if _, data, err := conn.Read(readCtx); err == nil {
+		t.Errorf("expected no messages on silent connection, got %s", data)
+	}
+}
@@ -0,0 +1,397 @@
+package twitchwstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Subscription statuses reported by the Helix subscription endpoints.
+const (
+	StatusEnabled               = "enabled"
+	StatusWebSocketDisconnected = "websocket_disconnected"
+)
+
+// createSubscriptionRequest is the body of a create subscription request.
+type createSubscriptionRequest struct {
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Transport Transport         `json:"transport"`
+}
+
+// subscriptionsResponse is the body of the create and get subscriptions responses.
+type subscriptionsResponse struct {
+	Data         []Subscription `json:"data"`
+	Total        int            `json:"total"`
+	TotalCost    int            `json:"total_cost"`
+	MaxTotalCost int            `json:"max_total_cost"`
+	Pagination   *pagination    `json:"pagination,omitempty"`
+}
+
+// pagination is the pagination of the get subscriptions response, all the subscriptions fit a single page.
+type pagination struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// helixError is the body of a Helix error response.
+type helixError struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Trigger sends a notification with the event, which is marshalled to JSON, to every session holding an enabled
+// subscription of the type whose condition matches the event. Condition fields missing from the event match any
+// value, so e.g. a channel.follow event is routed by its broadcaster_user_id, and so does the moderator_user_id naming
+// the moderator who authorized the subscription. Returns the number of notifications sent.
+func (s *Server) Trigger(subType string, event any) (int, error) {
+	data, err := json.Marshal(event)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// events other than JSON objects match every condition, numbers are kept as is to compare them with conditions
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	_ = dec.Decode(&fields)
+
+	type target struct {
+		session *Session
+		sub     Subscription
+	}
+
+	var targets []target
+
+	s.mu.Lock()
+	for _, sub := range s.subscriptions {
+		session, ok := s.active[sub.Transport.SessionID]
+
+		if ok && sub.Type == subType && sub.Status == StatusEnabled && conditionMatches(subType, sub.Condition, fields) {
+			targets = append(targets, target{session: session, sub: sub})
+		}
+	}
+	s.mu.Unlock()
+
+	var (
+		sent int
+		errs []error
+	)
+
+	for _, t := range targets {
+		sub := t.sub
+		// notifications carry the transport without the connection times
+		sub.Transport = Transport{Method: sub.Transport.Method, SessionID: sub.Transport.SessionID}
+
+		if _, err = t.session.Notify(sub, json.RawMessage(data)); err != nil {
+			if !errors.Is(err, ErrSessionClosed) {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		sent++
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// Subscriptions returns the subscriptions created with the Helix endpoints in the order of creation.
+func (s *Server) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]Subscription, len(s.subscriptions))
+
+	for i, sub := range s.subscriptions {
+		subs[i] = sub
+		subs[i].Condition = maps.Clone(sub.Condition)
+	}
+
+	return subs
+}
+
+// handleCreateSubscription creates a WebSocket subscription for a connected session.
+func (s *Server) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req createSubscriptionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeHelixError(w, http.StatusBadRequest, "malformed request body: "+err.Error())
+		return
+	}
+
+	if err := validateSubscription(&req); err != nil {
+		writeHelixError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.active[req.Transport.SessionID]
+
+	if !ok {
+		writeHelixError(w, http.StatusBadRequest, "websocket transport session does not exist or has already disconnected")
+		return
+	}
+
+	sessionSubscriptions := 0
+
+	for _, sub := range s.subscriptions {
+		if sub.Status != StatusEnabled || sub.Transport.SessionID != req.Transport.SessionID {
+			continue
+		}
+
+		if sub.Type == req.Type && sub.Version == req.Version && maps.Equal(sub.Condition, req.Condition) {
+			writeHelixError(w, http.StatusConflict, "subscription already exists")
+			return
+		}
+
+		sessionSubscriptions++
+	}
+
+	if sessionSubscriptions >= s.maxSessionSubscriptions {
+		writeHelixError(w, http.StatusTooManyRequests, "websocket transport session subscription limit exceeded")
+		return
+	}
+
+	token := r.Header.Get("Authorization")
+	cost := s.subscriptionCost(req.Condition)
+
+	if s.totalCost(token)+cost > s.maxTotalCost {
+		writeHelixError(w, http.StatusTooManyRequests, "total cost exceeded")
+		return
+	}
+
+	sub := Subscription{
+		ID:        newID(),
+		Status:    StatusEnabled,
+		Type:      req.Type,
+		Version:   req.Version,
+		Cost:      cost,
+		Condition: req.Condition,
+		Transport: Transport{
+			Method:      "websocket",
+			SessionID:   session.id,
+			ConnectedAt: session.connectedAt.UTC().Format(time.RFC3339Nano),
+		},
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		token:     token,
+	}
+	s.subscriptions = append(s.subscriptions, sub)
+
+	writeHelixJSON(w, http.StatusAccepted, s.response([]Subscription{sub}, token))
+}
+
+// handleGetSubscriptions lists the subscriptions filtered by status, type or user_id, at most one filter at a time.
+func (s *Server) handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filters := 0
+
+	for _, key := range []string{"status", "type", "user_id"} {
+		if query.Has(key) {
+			filters++
+		}
+	}
+
+	if filters > 1 {
+		writeHelixError(w, http.StatusBadRequest, "only one of status, type and user_id may be specified")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]Subscription, 0, len(s.subscriptions))
+
+	for _, sub := range s.subscriptions {
+		if subscriptionMatches(sub, query.Get("status"), query.Get("type"), query.Get("user_id")) {
+			data = append(data, sub)
+		}
+	}
+
+	resp := s.response(data, r.Header.Get("Authorization"))
+	resp.Pagination = &pagination{}
+
+	writeHelixJSON(w, http.StatusOK, resp)
+}
+
+// handleDeleteSubscription deletes the subscription with the ID from the id query parameter.
+func (s *Server) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	if id == "" {
+		writeHelixError(w, http.StatusBadRequest, "missing required parameter id")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.subscriptions {
+		if sub.ID == id {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+	}
+
+	writeHelixError(w, http.StatusNotFound, "subscription not found")
+}
+
+// disconnectSubscriptions disables the subscriptions of the session that has been disconnected. Must be called
+// with mu held.
+func (s *Server) disconnectSubscriptions(id string) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	for i := range s.subscriptions {
+		sub := &s.subscriptions[i]
+
+		if sub.Transport.SessionID == id && sub.Status == StatusEnabled {
+			sub.Status = StatusWebSocketDisconnected
+			sub.Transport.DisconnectedAt = now
+		}
+	}
+}
+
+// subscriptionCost returns the cost of a subscription with the condition: nothing if the condition names a user
+// who authorized the application, 1 otherwise.
+func (s *Server) subscriptionCost(condition map[string]string) int {
+	for key, value := range condition {
+		if _, ok := s.authorizedUsers[value]; ok && strings.HasSuffix(key, "user_id") {
+			return 0
+		}
+	}
+
+	return 1
+}
+
+// totalCost returns the total cost of the enabled subscriptions created with the access token. Must be called
+// with mu held.
+func (s *Server) totalCost(token string) int {
+	cost := 0
+
+	for _, sub := range s.subscriptions {
+		if sub.Status == StatusEnabled && sub.token == token {
+			cost += sub.Cost
+		}
+	}
+
+	return cost
+}
+
+// response creates a subscriptions response with the data and the current totals of the access token. Must be
+// called with mu held.
+func (s *Server) response(data []Subscription, token string) subscriptionsResponse {
+	return subscriptionsResponse{
+		Data:         data,
+		Total:        len(s.subscriptions),
+		TotalCost:    s.totalCost(token),
+		MaxTotalCost: s.maxTotalCost,
+	}
+}
+
+// validateSubscription checks the fields of a create subscription request.
+func validateSubscription(req *createSubscriptionRequest) error {
+	if req.Type == "" || req.Version == "" {
+		return errors.New("missing subscription type or version")
+	}
+
+	if len(req.Condition) == 0 {
+		return errors.New("missing condition")
+	}
+
+	for key, value := range req.Condition {
+		if value == "" {
+			return fmt.Errorf("condition field %s is empty", key)
+		}
+	}
+
+	if err := validateCondition(req.Type, req.Condition); err != nil {
+		return err
+	}
+
+	if req.Transport.Method != "websocket" {
+		return fmt.Errorf("unsupported transport method %q", req.Transport.Method)
+	}
+
+	if req.Transport.SessionID == "" {
+		return errors.New("missing websocket transport session_id")
+	}
+
+	return nil
+}
+
+// subscriptionMatches reports whether the subscription passes the get subscriptions filters, empty ones match any
+// subscription. The user ID matches any condition field ending with user_id.
+func subscriptionMatches(sub Subscription, status, subType, userID string) bool {
+	if status != "" && sub.Status != status || subType != "" && sub.Type != subType {
+		return false
+	}
+
+	if userID == "" {
+		return true
+	}
+
+	for key, value := range sub.Condition {
+		if strings.HasSuffix(key, "user_id") && value == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// conditionMatches reports whether the event fields match the routing fields of the subscription condition, e.g.
+// the moderator who authorized a channel.moderate subscription does not filter the actions of other moderators.
+// Non-string fields, e.g. numeric IDs, are compared in their JSON representation.
+func conditionMatches(subType string, condition map[string]string, fields map[string]any) bool {
+	conditionFields := subscriptionConditions[subType]
+
+	for key, value := range condition {
+		if !conditionFields.routes(key) {
+			continue
+		}
+
+		field, ok := fields[key]
+
+		if !ok || field == nil {
+			continue
+		}
+
+		if s, ok := field.(string); ok {
+			if s != value {
+				return false
+			}
+
+			continue
+		}
+
+		if data, err := json.Marshal(field); err != nil || string(data) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeHelixJSON writes the response body with the status code.
+func writeHelixJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeHelixError writes a Helix error response.
+func writeHelixError(w http.ResponseWriter, status int, message string) {
+	writeHelixJSON(w, status, helixError{Error: http.StatusText(status), Status: status, Message: message})
+}
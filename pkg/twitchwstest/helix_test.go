@@ -0,0 +1,419 @@
+package twitchwstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// helixRequest sends a request to the subscription endpoints and decodes the response body into out, if any.
+func helixRequest(t *testing.T, method, url string, body any, out any) int {
+	t.Helper()
+
+	return authorizedHelixRequest(t, "", method, url, body, out)
+}
+
+// authorizedHelixRequest sends a Helix request with the access token in the Authorization header, if set.
+func authorizedHelixRequest(t *testing.T, token, method, url string, body any, out any) int {
+	t.Helper()
+
+	var data []byte
+
+	if body != nil {
+		var err error
+
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// subscribe creates a channel.follow subscription of the broadcaster for the session.
+func subscribe(t *testing.T, srv *Server, sessionID, broadcaster string) (int, subscriptionsResponse) {
+	t.Helper()
+
+	var resp subscriptionsResponse
+	status := helixRequest(t, http.MethodPost, srv.HelixURL()+"/eventsub/subscriptions", map[string]any{
+		"type":      "channel.follow",
+		"version":   "2",
+		"condition": map[string]string{"broadcaster_user_id": broadcaster, "moderator_user_id": broadcaster},
+		"transport": map[string]string{"method": "websocket", "session_id": sessionID},
+	}, &resp)
+
+	return status, resp
+}
+
+func TestHelixCreateAndGetSubscriptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, session := newFaultSession(t, ctx)
+	srv := session.server
+	status, created := subscribe(t, srv, session.ID(), "1337")
+
+	if status != http.StatusAccepted || len(created.Data) != 1 || created.TotalCost != 1 || created.MaxTotalCost != 10 {
+		t.Fatalf("unexpected create response %d: %+v", status, created)
+	}
+
+	sub := created.Data[0]
+
+	if sub.Status != StatusEnabled || sub.Transport.SessionID != session.ID() || sub.Transport.ConnectedAt == "" {
+		t.Errorf("unexpected subscription: %+v", sub)
+	}
+
+	var listed subscriptionsResponse
+
+	for _, query := range []string{"", "?type=channel.follow", "?user_id=1337", "?status=enabled"} {
+		status = helixRequest(t, http.MethodGet, srv.HelixURL()+"/eventsub/subscriptions"+query, nil, &listed)
+
+		if status != http.StatusOK || len(listed.Data) != 1 || listed.Data[0].ID != sub.ID || listed.Pagination == nil {
+			t.Errorf("query %q: unexpected get response %d: %+v", query, status, listed)
+		}
+	}
+
+	status = helixRequest(t, http.MethodGet, srv.HelixURL()+"/eventsub/subscriptions?user_id=42", nil, &listed)
+
+	if status != http.StatusOK || len(listed.Data) != 0 || listed.Total != 1 {
+		t.Errorf("unexpected filtered get response %d: %+v", status, listed)
+	}
+
+	status = helixRequest(t, http.MethodGet, srv.HelixURL()+"/eventsub/subscriptions?type=a&status=b", nil, &helixError{})
+
+	if status != http.StatusBadRequest {
+		t.Errorf("expected status %d for multiple filters, got %d", http.StatusBadRequest, status)
+	}
+
+	_ = conn.CloseNow()
+	<-session.Done()
+
+	// the session is disconnected once the server notices the closed connection
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if srv.Subscriptions()[0].Status == StatusWebSocketDisconnected {
+			break
+		}
+	}
+
+	status = helixRequest(t, http.MethodGet, srv.HelixURL()+"/eventsub/subscriptions", nil, &listed)
+
+	if len(listed.Data) != 1 || listed.Data[0].Status != StatusWebSocketDisconnected ||
+		listed.Data[0].Transport.DisconnectedAt == "" || listed.TotalCost != 0 {
+		t.Errorf("unexpected get response after disconnect %d: %+v", status, listed)
+	}
+}
+
+func TestHelixCreateSubscriptionValidation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, session := newFaultSession(t, ctx)
+	url := session.server.HelixURL() + "/eventsub/subscriptions"
+	transport := map[string]string{"method": "websocket", "session_id": session.ID()}
+	condition := map[string]string{"broadcaster_user_id": "1337"}
+
+	tests := []struct {
+		name string
+		body any
+	}{
+		{"malformed body", "{"},
+		{"missing type", map[string]any{"version": "1", "condition": condition, "transport": transport}},
+		{"missing condition", map[string]any{"type": "stream.online", "version": "1", "transport": transport}},
+		{"empty condition field", map[string]any{
+			"type": "stream.online", "version": "1",
+			"condition": map[string]string{"broadcaster_user_id": ""}, "transport": transport,
+		}},
+		{"missing condition field", map[string]any{
+			"type": "channel.follow", "version": "2", "condition": condition, "transport": transport,
+		}},
+		{"unsupported condition field", map[string]any{
+			"type": "stream.online", "version": "1",
+			"condition": map[string]string{"broadcaster_user_id": "1337", "user_id": "1337"}, "transport": transport,
+		}},
+		{"webhook transport", map[string]any{
+			"type": "stream.online", "version": "1", "condition": condition,
+			"transport": map[string]string{"method": "webhook", "callback": "https://example.com"},
+		}},
+		{"unknown session", map[string]any{
+			"type": "stream.online", "version": "1", "condition": condition,
+			"transport": map[string]string{"method": "websocket", "session_id": "unknown"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp helixError
+
+			if status := helixRequest(t, http.MethodPost, url, tt.body, &resp); status != http.StatusBadRequest ||
+				resp.Status != http.StatusBadRequest || resp.Message == "" {
+				t.Errorf("expected bad request, got %d: %+v", status, resp)
+			}
+		})
+	}
+
+	if subs := session.server.Subscriptions(); len(subs) != 0 {
+		t.Errorf("expected no subscriptions, got %+v", subs)
+	}
+}
+
+func TestHelixSubscriptionLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer(WithMaxTotalCost(3), WithMaxSessionSubscriptions(2))
+	t.Cleanup(srv.Close)
+
+	sessions := make([]*Session, 2)
+
+	for i := range sessions {
+		dial(t, ctx, srv.URL())
+
+		var err error
+
+		if sessions[i], err = srv.WaitSession(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		session     *Session
+		broadcaster string
+		status      int
+	}{
+		{sessions[0], "1", http.StatusAccepted},
+		{sessions[0], "1", http.StatusConflict},
+		{sessions[0], "2", http.StatusAccepted},
+		{sessions[0], "3", http.StatusTooManyRequests},
+		{sessions[1], "1", http.StatusAccepted},
+		{sessions[1], "2", http.StatusTooManyRequests},
+	}
+
+	for i, step := range steps {
+		if status, _ := subscribe(t, srv, step.session.ID(), step.broadcaster); status != step.status {
+			t.Errorf("step %d: expected status %d, got %d", i, step.status, status)
+		}
+	}
+
+	subs := srv.Subscriptions()
+	url := srv.HelixURL() + "/eventsub/subscriptions?id="
+
+	if status := helixRequest(t, http.MethodDelete, url+subs[0].ID, nil, nil); status != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, status)
+	}
+
+	if status := helixRequest(t, http.MethodDelete, url+subs[0].ID, nil, &helixError{}); status != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+
+	if status := helixRequest(t, http.MethodDelete, url, nil, &helixError{}); status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+
+	if status, _ := subscribe(t, srv, sessions[1].ID(), "2"); status != http.StatusAccepted {
+		t.Errorf("expected status %d after delete, got %d", http.StatusAccepted, status)
+	}
+}
+
+func TestHelixSubscriptionCost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer(WithMaxTotalCost(1), WithAuthorizedUsers("1"))
+	t.Cleanup(srv.Close)
+
+	dial(t, ctx, srv.URL())
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		token       string
+		broadcaster string
+		status      int
+		cost        int
+		totalCost   int
+	}{
+		{"a", "1", http.StatusAccepted, 0, 0},
+		{"a", "2", http.StatusAccepted, 1, 1},
+		{"a", "3", http.StatusTooManyRequests, 0, 0},
+		// the total cost is limited per access token
+		{"b", "3", http.StatusAccepted, 1, 1},
+	}
+
+	for i, step := range steps {
+		var resp subscriptionsResponse
+		status := authorizedHelixRequest(t, step.token, http.MethodPost, srv.HelixURL()+"/eventsub/subscriptions",
+			map[string]any{
+				"type":      "channel.follow",
+				"version":   "2",
+				"condition": map[string]string{"broadcaster_user_id": step.broadcaster, "moderator_user_id": step.broadcaster},
+				"transport": map[string]string{"method": "websocket", "session_id": session.ID()},
+			}, &resp)
+
+		if status != step.status {
+			t.Fatalf("step %d: expected status %d, got %d", i, step.status, status)
+		}
+
+		if status == http.StatusAccepted && (resp.Data[0].Cost != step.cost || resp.TotalCost != step.totalCost) {
+			t.Errorf("step %d: expected cost %d and total cost %d, got %d and %d",
+				i, step.cost, step.totalCost, resp.Data[0].Cost, resp.TotalCost)
+		}
+	}
+
+	var listed subscriptionsResponse
+
+	authorizedHelixRequest(t, "a", http.MethodGet, srv.HelixURL()+"/eventsub/subscriptions", nil, &listed)
+
+	if listed.Total != 3 || listed.TotalCost != 1 {
+		t.Errorf("expected 3 subscriptions with total cost 1, got %d with %d", listed.Total, listed.TotalCost)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+
+	sessions := make(map[string]*Session)
+
+	for _, broadcaster := range []string{"1", "2"} {
+		conn := dial(t, ctx, srv.URL())
+		read(t, ctx, conn)
+		session, err := srv.WaitSession(ctx)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status, _ := subscribe(t, srv, session.ID(), broadcaster); status != http.StatusAccepted {
+			t.Fatalf("unexpected create status %d", status)
+		}
+
+		sessions[broadcaster] = session
+
+		t.Run(fmt.Sprintf("broadcaster %s", broadcaster), func(t *testing.T) {
+			sent, err := srv.Trigger("channel.follow", map[string]string{"broadcaster_user_id": broadcaster, "user_id": "42"})
+
+			if err != nil || sent != 1 {
+				t.Fatalf("expected 1 notification, got %d, %v", sent, err)
+			}
+
+			m := read(t, ctx, conn)
+
+			if m.Metadata.MessageType != "notification" || m.Payload.Subscription.Transport.SessionID != session.ID() ||
+				m.Payload.Subscription.Transport.ConnectedAt != "" ||
+				m.Payload.Subscription.Condition["broadcaster_user_id"] != broadcaster {
+				t.Errorf("unexpected notification: %+v", m)
+			}
+		})
+	}
+
+	if sent, err := srv.Trigger("channel.follow", map[string]string{"broadcaster_user_id": "3"}); err != nil || sent != 0 {
+		t.Errorf("expected no notifications for unsubscribed broadcaster, got %d, %v", sent, err)
+	}
+
+	if sent, err := srv.Trigger("stream.online", struct{}{}); err != nil || sent != 0 {
+		t.Errorf("expected no notifications for unsubscribed type, got %d, %v", sent, err)
+	}
+
+	if sent, err := srv.Trigger("channel.follow", map[string]any{"broadcaster_user_id": 2}); err != nil || sent != 1 {
+		t.Errorf("expected 1 notification for numeric condition field, got %d, %v", sent, err)
+	}
+
+	if sent, err := srv.Trigger("channel.follow", struct{}{}); err != nil || sent != len(sessions) {
+		t.Errorf("expected %d notifications for event without condition fields, got %d, %v", len(sessions), sent, err)
+	}
+}
+
+func TestTriggerActingModerator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subTypes := []string{
+		"automod.settings.update",
+		"automod.terms.update",
+		"channel.moderate",
+		"channel.shield_mode.begin",
+		"channel.shield_mode.end",
+		"channel.shoutout.create",
+		"channel.suspicious_user.update",
+		"channel.warning.send",
+	}
+
+	for _, subType := range subTypes {
+		t.Run(subType, func(t *testing.T) {
+			status := helixRequest(t, http.MethodPost, srv.HelixURL()+"/eventsub/subscriptions", map[string]any{
+				"type":      subType,
+				"version":   "1",
+				"condition": map[string]string{"broadcaster_user_id": "1", "moderator_user_id": "10"},
+				"transport": map[string]string{"method": "websocket", "session_id": session.ID()},
+			}, nil)
+
+			if status != http.StatusAccepted {
+				t.Fatalf("unexpected create status %d", status)
+			}
+
+			// the action is taken by another moderator than the one who authorized the subscription
+			sent, err := srv.Trigger(subType, map[string]string{"broadcaster_user_id": "1", "moderator_user_id": "20"})
+
+			if err != nil || sent != 1 {
+				t.Fatalf("expected 1 notification, got %d, %v", sent, err)
+			}
+
+			if m := read(t, ctx, conn); m.Metadata.SubscriptionType != subType {
+				t.Errorf("unexpected notification: %+v", m)
+			}
+
+			sent, err = srv.Trigger(subType, map[string]string{"broadcaster_user_id": "2", "moderator_user_id": "10"})
+
+			if err != nil || sent != 0 {
+				t.Errorf("expected no notifications for another broadcaster, got %d, %v", sent, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+// Package twitchwstest provides an in-process EventSub WebSocket server for testing Twitch EventSub clients.
+//
+// The server speaks the EventSub WebSocket protocol: it sends a session_welcome message to every connected client,
+// keeps the session alive with session_keepalive messages and lets tests script notifications, revocations,
+// session_reconnect messages and closures with Twitch close codes:
+//
+//	srv := twitchwstest.NewServer(twitchwstest.WithKeepalive(10))
+//	defer srv.Close()
+//
+//	c := twitchws.NewClient(srv.URL())
+//	// connect the client
+//
+//	s, err := srv.WaitSession(ctx)
+//	// handle error
+//
+//	id, err := s.Notify(twitchwstest.Subscription{Type: "channel.follow", Version: "2"}, event)
+//
+// Sessions can also inject faults to exercise the client recovery: skipped keepalives, delayed messages, duplicate
+// message IDs, out-of-order timestamps, TCP resets, binary and malformed frames, and reconnect URLs that refuse
+// connections or never send a welcome message:
+//
+//	s.PauseKeepalive()
+//	err = s.Resend(id)
+//	s.Reset()
+//	err = s.ReconnectTo(srv.SilentURL())
+//
+// The server also emulates the Helix EventSub subscription endpoints at HelixURL() + "/eventsub/subscriptions":
+// subscriptions created for connected sessions receive the events passed to Trigger:
+//
+//	n, err := srv.Trigger("channel.follow", eventsub.ChannelFollowEvent{BroadcasterUserID: "1337"})
+package twitchwstest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Paths the server accepts WebSocket connections on.
+const (
+	// PathWebSocket is the path clients connect to in order to start a new session.
+	PathWebSocket = "/ws"
+
+	// PathReconnect is the path prefix of the reconnect URLs sent with session_reconnect messages.
+	PathReconnect = "/ws/reconnect/"
+
+	// PathSilent is the path of connections that are accepted but never receive a welcome message.
+	PathSilent = "/ws/silent"
+
+	// PathRefused is the path of connections that are refused without the WebSocket handshake.
+	PathRefused = "/ws/refused"
+
+	// PathSubscriptions is the path of the Helix EventSub subscription endpoints.
+	PathSubscriptions = "/helix/eventsub/subscriptions"
+)
+
+// netConnKey is the request context key of the underlying network connection.
+type netConnKey struct{}
+
+// Default values of the server options.
+const (
+	defaultKeepaliveSeconds        = 10
+	defaultMaxTotalCost            = 10
+	defaultMaxSessionSubscriptions = 300
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// Server is an in-process EventSub WebSocket server built on httptest.Server.
+type Server struct {
+	srv *httptest.Server
+
+	// keepaliveSeconds is the keepalive timeout reported in the welcome messages.
+	keepaliveSeconds int
+
+	// keepaliveInterval is the time without messages after which a session_keepalive message is sent.
+	keepaliveInterval time.Duration
+
+	// onSession is invoked in its own goroutine for every new session after the welcome message is sent.
+	onSession func(*Session)
+
+	// maxTotalCost limits the total cost of the enabled subscriptions created with a single access token.
+	maxTotalCost int
+
+	// authorizedUsers holds the IDs of the users who authorized the application, their subscriptions cost nothing.
+	authorizedUsers map[string]struct{}
+
+	// maxSessionSubscriptions limits the number of enabled subscriptions of a single session.
+	maxSessionSubscriptions int
+
+	// sessionAdded signals WaitSession that a new session has been queued.
+	sessionAdded chan struct{}
+
+	// mu guards sessions, active, conns, subscriptions and closed.
+	mu sync.Mutex
+
+	// sessions queues the new sessions not yet returned by WaitSession.
+	sessions []*Session
+
+	// active maps session IDs to their current sessions, used to hand sessions over to reconnect URLs.
+	active map[string]*Session
+
+	// conns holds all the open WebSocket connections including the silent ones, so Close can close them.
+	conns map[*websocket.Conn]struct{}
+
+	// subscriptions holds the subscriptions created with the Helix endpoints in the order of creation.
+	subscriptions []Subscription
+
+	// closed is set by Close, the connections established afterwards are closed right away.
+	closed bool
+
+	// wg tracks the connection handlers, so Close waits for them to finish.
+	wg sync.WaitGroup
+}
+
+// WithKeepalive sets the keepalive timeout in seconds reported in the welcome messages, 10 by default.
+// The server sends a session_keepalive message whenever no other message has been sent for that long.
+func WithKeepalive(seconds int) Option {
+	return func(s *Server) {
+		s.keepaliveSeconds = seconds
+	}
+}
+
+// WithKeepaliveInterval overrides the time without messages after which a session_keepalive message is sent,
+// e.g. to keep fast tests going. A zero or negative interval disables keepalive messages.
+func WithKeepaliveInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.keepaliveInterval = interval
+	}
+}
+
+// WithSessionHandler sets the function invoked in its own goroutine for every new session after the welcome message
+// is sent, e.g. to script the messages every client receives.
+func WithSessionHandler(fn func(*Session)) Option {
+	return func(s *Server) {
+		s.onSession = fn
+	}
+}
+
+// WithMaxTotalCost sets the maximum total cost of the enabled subscriptions created with a single access token,
+// 10 by default like the Twitch limit for WebSocket transports. The access token is the Authorization header of
+// the Helix requests, the requests without one share the limit. Every subscription costs 1 unless its condition
+// names one of the users set with WithAuthorizedUsers.
+func WithMaxTotalCost(cost int) Option {
+	return func(s *Server) {
+		s.maxTotalCost = cost
+	}
+}
+
+// WithAuthorizedUsers sets the IDs of the users who authorized the application. Like on Twitch, the subscriptions
+// whose condition names one of them, e.g. as broadcaster_user_id, cost nothing.
+func WithAuthorizedUsers(userIDs ...string) Option {
+	return func(s *Server) {
+		for _, id := range userIDs {
+			s.authorizedUsers[id] = struct{}{}
+		}
+	}
+}
+
+// WithMaxSessionSubscriptions sets the maximum number of enabled subscriptions of a single session, 300 by default
+// like the Twitch limit.
+func WithMaxSessionSubscriptions(n int) Option {
+	return func(s *Server) {
+		s.maxSessionSubscriptions = n
+	}
+}
+
+// NewServer starts a server configured with the specified options. The server must be closed with Close.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		keepaliveSeconds:        defaultKeepaliveSeconds,
+		maxTotalCost:            defaultMaxTotalCost,
+		maxSessionSubscriptions: defaultMaxSessionSubscriptions,
+		authorizedUsers:         make(map[string]struct{}),
+		sessionAdded:            make(chan struct{}, 1),
+		active:                  make(map[string]*Session),
+		conns:                   make(map[*websocket.Conn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.keepaliveInterval == 0 {
+		s.keepaliveInterval = time.Duration(s.keepaliveSeconds) * time.Second
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathWebSocket, s.handleWebSocket)
+	mux.HandleFunc(PathReconnect, s.handleReconnect)
+	mux.HandleFunc(PathSilent, s.handleSilent)
+	mux.HandleFunc(PathRefused, handleRefused)
+	mux.HandleFunc("POST "+PathSubscriptions, s.handleCreateSubscription)
+	mux.HandleFunc("GET "+PathSubscriptions, s.handleGetSubscriptions)
+	mux.HandleFunc("DELETE "+PathSubscriptions, s.handleDeleteSubscription)
+	s.srv = httptest.NewUnstartedServer(mux)
+	s.srv.Config.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		return context.WithValue(ctx, netConnKey{}, conn)
+	}
+	s.srv.Start()
+
+	return s
+}
+
+// URL returns the WebSocket URL clients connect to in order to start a new session.
+func (s *Server) URL() string {
+	return s.wsURL(PathWebSocket)
+}
+
+// Close closes all the connections and shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*websocket.Conn, 0, len(s.conns))
+
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	// hijacked WebSocket connections are not closed by httptest.Server
+	for _, conn := range conns {
+		_ = conn.CloseNow()
+	}
+
+	s.wg.Wait()
+}
+
+// HelixURL returns the base URL of the Helix endpoints, so the subscription endpoints are served at
+// HelixURL() + "/eventsub/subscriptions".
+func (s *Server) HelixURL() string {
+	return s.srv.URL + "/helix"
+}
+
+// WaitSession returns the next session established by a client connecting to URL, in the order of connection.
+// Sessions are queued until they are taken, however many clients connect in the meantime. Sessions handed over
+// to a reconnect URL are not returned again.
+func (s *Server) WaitSession(ctx context.Context) (*Session, error) {
+	for {
+		if session, ok := s.dequeueSession(); ok {
+			return session, nil
+		}
+
+		select {
+		case <-s.sessionAdded:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// enqueueSession queues the new session for WaitSession, however many sessions are already waiting.
+func (s *Server) enqueueSession(session *Session) {
+	s.mu.Lock()
+	s.sessions = append(s.sessions, session)
+	s.mu.Unlock()
+	s.notifySessionAdded()
+}
+
+// dequeueSession takes the oldest queued session, if any. The other waiters are woken up if more sessions
+// are left in the queue.
+func (s *Server) dequeueSession() (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.sessions) == 0 {
+		return nil, false
+	}
+
+	session := s.sessions[0]
+	s.sessions = s.sessions[1:]
+
+	if len(s.sessions) > 0 {
+		s.notifySessionAdded()
+	}
+
+	return session, true
+}
+
+// notifySessionAdded wakes up a single WaitSession call without blocking.
+func (s *Server) notifySessionAdded() {
+	select {
+	case s.sessionAdded <- struct{}{}:
+	default:
+	}
+}
+
+// Session returns the current connection of the session with the specified ID, e.g. after it has been handed over
+// to a reconnect URL.
+func (s *Server) Session(id string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.active[id]
+
+	return session, ok
+}
+
+// wsURL returns the WebSocket URL of the path.
+func (s *Server) wsURL(path string) string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http") + path
+}
+
+// handleWebSocket starts a new session.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	session, ok := s.accept(w, r, newID(), nil)
+
+	if !ok {
+		return
+	}
+
+	s.enqueueSession(session)
+	s.serve(r.Context(), session, nil)
+}
+
+// handleReconnect hands the session with the ID from the URL path over to the new connection.
+func (s *Server) handleReconnect(w http.ResponseWriter, r *http.Request) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	id := strings.TrimPrefix(r.URL.Path, PathReconnect)
+	previous, ok := s.Session(id)
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	session, ok := s.accept(w, r, id, previous)
+
+	if !ok {
+		return
+	}
+
+	s.serve(r.Context(), session, previous)
+}
+
+// handleSilent accepts the connection but never sends a welcome message, the client messages are ignored.
+func (s *Server) handleSilent(w http.ResponseWriter, r *http.Request) {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	conn, ok := s.upgrade(w, r)
+
+	if !ok {
+		return
+	}
+
+	defer s.release(conn)
+
+	for {
+		if _, _, err := conn.Read(r.Context()); err != nil {
+			return
+		}
+	}
+}
+
+// handleRefused refuses the connection without the WebSocket handshake.
+func handleRefused(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// accept upgrades the request to a WebSocket connection of the session with the specified ID and sends the welcome
+// message. The previous connection of a session handed over to a reconnect URL is restored if the welcome message
+// cannot be sent. Returns false if the connection cannot be established.
+func (s *Server) accept(w http.ResponseWriter, r *http.Request, id string, previous *Session) (*Session, bool) {
+	conn, ok := s.upgrade(w, r)
+
+	if !ok {
+		return nil, false
+	}
+
+	netConn, _ := r.Context().Value(netConnKey{}).(net.Conn)
+	session := newSession(s, conn, netConn, id)
+
+	// the session is registered before the welcome, so the client may subscribe as soon as it is welcomed
+	s.mu.Lock()
+	s.active[id] = session
+	s.mu.Unlock()
+
+	if err := session.sendWelcome(); err != nil {
+		s.release(conn)
+		s.mu.Lock()
+		if s.active[id] == session {
+			if previous != nil {
+				s.active[id] = previous
+			} else {
+				delete(s.active, id)
+			}
+		}
+		s.mu.Unlock()
+
+		return nil, false
+	}
+
+	return session, true
+}
+
+// upgrade upgrades the request to a tracked WebSocket connection. Returns false if the connection cannot be
+// established or the server is closed.
+func (s *Server) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, bool) {
+	conn, err := websocket.Accept(w, r, nil)
+
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		_ = conn.CloseNow()
+		return nil, false
+	}
+
+	s.conns[conn] = struct{}{}
+
+	return conn, true
+}
+
+// release closes the connection and stops tracking it.
+func (s *Server) release(conn *websocket.Conn) {
+	_ = conn.CloseNow()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+// serve runs the session until its connection is closed. The previous connection of a session handed over
+// to a reconnect URL is closed once the new connection has been welcomed.
+func (s *Server) serve(ctx context.Context, session *Session, previous *Session) {
+	if previous != nil {
+		previous.closeWith(websocket.StatusNormalClosure, "")
+	}
+
+	if s.onSession != nil {
+		go s.onSession(session)
+	}
+
+	session.run(ctx)
+	s.release(session.conn)
+
+	s.mu.Lock()
+	if s.active[session.id] == session {
+		delete(s.active, session.id)
+		s.disconnectSubscriptions(session.id)
+	}
+	s.mu.Unlock()
+}
+
+// newID returns a random identifier for sessions, subscriptions and messages.
+func newID() string {
+	const idSize = 16
+	b := make([]byte, idSize)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
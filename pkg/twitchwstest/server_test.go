@@ -0,0 +1,264 @@
+package twitchwstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+type message struct {
+	Metadata Metadata `json:"metadata"`
+	Payload  struct {
+		Session      sessionPayload  `json:"session"`
+		Subscription Subscription    `json:"subscription"`
+		Event        json.RawMessage `json:"event"`
+	} `json:"payload"`
+}
+
+func dial(t *testing.T, ctx context.Context, url string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.Dial(ctx, url, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.CloseNow()
+	})
+
+	return conn
+}
+
+func read(t *testing.T, ctx context.Context, conn *websocket.Conn) message {
+	t.Helper()
+
+	_, data, err := conn.Read(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m message
+
+	if err = json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestServerWelcomeAndKeepalive(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer(WithKeepalive(30), WithKeepaliveInterval(50*time.Millisecond))
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL())
+	welcome := read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if welcome.Metadata.MessageType != "session_welcome" || welcome.Payload.Session.ID != session.ID() ||
+		*welcome.Payload.Session.KeepaliveTimeoutSeconds != 30 {
+		t.Errorf("unexpected welcome message: %+v", welcome)
+	}
+
+	for range 2 {
+		if m := read(t, ctx, conn); m.Metadata.MessageType != "session_keepalive" {
+			t.Errorf("expected keepalive message, got %s", m.Metadata.MessageType)
+		}
+	}
+}
+
+func TestServerNotifyAndRevoke(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := Subscription{Type: "channel.follow", Version: "2", Condition: map[string]string{"broadcaster_user_id": "1337"}}
+
+	if _, err = session.Notify(sub, map[string]string{"user_id": "42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := read(t, ctx, conn)
+
+	if m.Metadata.MessageType != "notification" || m.Metadata.SubscriptionType != "channel.follow" ||
+		m.Metadata.SubscriptionVersion != "2" || m.Payload.Subscription.Transport.SessionID != session.ID() ||
+		m.Payload.Subscription.Status != "enabled" || string(m.Payload.Event) != `{"user_id":"42"}` {
+		t.Errorf("unexpected notification: %+v", m)
+	}
+
+	if _, err = session.Revoke(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if m = read(t, ctx, conn); m.Metadata.MessageType != "revocation" || m.Payload.Subscription.Status != "authorization_revoked" {
+		t.Errorf("unexpected revocation: %+v", m)
+	}
+}
+
+func TestServerReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := read(t, ctx, conn)
+
+	if m.Metadata.MessageType != "session_reconnect" || m.Payload.Session.ReconnectURL == nil {
+		t.Fatalf("unexpected reconnect message: %+v", m)
+	}
+
+	reconnected := dial(t, ctx, *m.Payload.Session.ReconnectURL)
+
+	if m = read(t, ctx, reconnected); m.Metadata.MessageType != "session_welcome" || m.Payload.Session.ID != session.ID() {
+		t.Errorf("unexpected reconnect welcome: %+v", m)
+	}
+
+	// the previous connection is closed by the server
+	if _, _, err = conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+		t.Errorf("expected previous connection to be closed normally, got %v", err)
+	}
+
+	current, ok := srv.Session(session.ID())
+
+	if !ok || current == session {
+		t.Error("expected the session to be handed over to the new connection")
+	}
+
+	if _, _, err = websocket.Dial(ctx, srv.wsURL(PathReconnect+"unknown"), nil); err == nil {
+		t.Error("expected reconnect to an unknown session to fail")
+	}
+}
+
+func TestServerWaitSessionQueue(t *testing.T) {
+	const clients = 40
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	welcomed := make(map[string]bool, clients)
+
+	// no session is taken until all the clients are connected
+	for range clients {
+		welcomed[read(t, ctx, dial(t, ctx, srv.URL())).Payload.Session.ID] = true
+	}
+
+	var wg sync.WaitGroup
+
+	sessions := make(chan *Session, clients)
+
+	for range clients {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if session, err := srv.WaitSession(ctx); err == nil {
+				sessions <- session
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(sessions)
+
+	for session := range sessions {
+		if !welcomed[session.ID()] {
+			t.Errorf("unexpected session %s", session.ID())
+		}
+
+		delete(welcomed, session.ID())
+	}
+
+	if len(welcomed) != 0 {
+		t.Errorf("%d sessions have not been returned", len(welcomed))
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+	session, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go session.Close(CloseNetworkTimeout, "network timeout")
+
+	_, _, err = conn.Read(ctx)
+
+	if websocket.CloseStatus(err) != CloseNetworkTimeout {
+		t.Errorf("expected close code %d, got %v", CloseNetworkTimeout, err)
+	}
+
+	<-session.Done()
+
+	if err = session.Keepalive(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("expected %v, got %v", ErrSessionClosed, err)
+	}
+}
+
+func TestServerInboundTraffic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv := NewServer()
+	defer srv.Close()
+
+	conn := dial(t, ctx, srv.URL())
+	read(t, ctx, conn)
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != CloseClientSentInboundTraffic {
+		t.Errorf("expected close code %d, got %v", CloseClientSentInboundTraffic, err)
+	}
+}
@@ -0,0 +1,405 @@
+package twitchwstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Close codes used by Twitch when it closes an EventSub WebSocket connection.
+const (
+	CloseInternalServerError       = 4000
+	CloseClientSentInboundTraffic  = 4001
+	CloseClientFailedPingPong      = 4002
+	CloseConnectionUnused          = 4003
+	CloseReconnectGraceTimeExpired = 4004
+	CloseNetworkTimeout            = 4005
+	CloseNetworkError              = 4006
+	CloseInvalidReconnect          = 4007
+)
+
+// writeTimeout limits the time a message write may take.
+const writeTimeout = 5 * time.Second
+
+// resendLimit is the number of the latest notification and revocation messages a session keeps for Resend.
+const resendLimit = 1024
+
+// Errors returned by the Session methods.
+var (
+	// ErrSessionClosed is returned when a message is sent to a closed session.
+	ErrSessionClosed = errors.New("session closed")
+
+	// ErrUnknownMessage is returned by Resend for a message ID that is not among the latest notification and
+	// revocation messages sent by the session.
+	ErrUnknownMessage = errors.New("unknown message")
+)
+
+// Subscription is an EventSub subscription as sent with notification and revocation messages.
+// Empty fields are filled with defaults bound to the session the message is sent to.
+type Subscription struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Cost      int               `json:"cost"`
+	Condition map[string]string `json:"condition"`
+	Transport Transport         `json:"transport"`
+	CreatedAt string            `json:"created_at"`
+
+	// token is the access token the subscription has been created with, which scopes its cost.
+	token string
+}
+
+// Transport is the transport of a WebSocket subscription. The connection times are reported by the Helix
+// subscription endpoints only.
+type Transport struct {
+	Method         string `json:"method"`
+	SessionID      string `json:"session_id"`
+	ConnectedAt    string `json:"connected_at,omitempty"`
+	DisconnectedAt string `json:"disconnected_at,omitempty"`
+}
+
+// Metadata is the metadata of a message sent by the server.
+type Metadata struct {
+	MessageID           string `json:"message_id"`
+	MessageType         string `json:"message_type"`
+	MessageTimestamp    string `json:"message_timestamp"`
+	SubscriptionType    string `json:"subscription_type,omitempty"`
+	SubscriptionVersion string `json:"subscription_version,omitempty"`
+}
+
+// sessionPayload is the session description sent with session_welcome and session_reconnect messages.
+type sessionPayload struct {
+	ID                      string  `json:"id"`
+	Status                  string  `json:"status"`
+	ConnectedAt             string  `json:"connected_at"`
+	KeepaliveTimeoutSeconds *int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            *string `json:"reconnect_url"`
+}
+
+// Session is a client connection served by a Server. All the methods are safe for concurrent use.
+type Session struct {
+	server      *Server
+	conn        *websocket.Conn
+	netConn     net.Conn
+	id          string
+	connectedAt time.Time
+	done        chan struct{}
+
+	// closeOnce guards the done channel.
+	closeOnce sync.Once
+
+	// keepalivePaused suppresses the session_keepalive messages while set.
+	keepalivePaused atomic.Bool
+
+	// mu guards lastSent, sent, sentOrder, delay and timestampOffset.
+	mu sync.Mutex
+
+	// lastSent is the time the last message has been sent at.
+	lastSent time.Time
+
+	// sent maps the IDs of the latest notification and revocation messages to their frames, kept for Resend.
+	sent map[string][]byte
+
+	// sentOrder holds the IDs of the frames in sent from the oldest, so the oldest is dropped past resendLimit.
+	sentOrder []string
+
+	// delay is the time every message is held back for before it is sent.
+	delay time.Duration
+
+	// timestampOffset shifts the generated message timestamps.
+	timestampOffset time.Duration
+}
+
+// newSession creates a session served over the connection.
+func newSession(server *Server, conn *websocket.Conn, netConn net.Conn, id string) *Session {
+	return &Session{
+		server:      server,
+		conn:        conn,
+		netConn:     netConn,
+		id:          id,
+		connectedAt: time.Now(),
+		done:        make(chan struct{}),
+		sent:        make(map[string][]byte),
+	}
+}
+
+// ID returns the session ID sent with the welcome message.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Done returns a channel closed once the connection has been closed.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Notify sends a notification message with the subscription and the event, which is marshalled to JSON.
+// Returns the message ID, e.g. to send the message again with Resend.
+func (s *Session) Notify(sub Subscription, event any) (string, error) {
+	sub = s.subscription(sub, StatusEnabled)
+	payload := map[string]any{"subscription": sub, "event": event}
+
+	return s.Send(Metadata{
+		MessageType:         "notification",
+		SubscriptionType:    sub.Type,
+		SubscriptionVersion: sub.Version,
+	}, payload)
+}
+
+// Revoke sends a revocation message for the subscription, its status is "authorization_revoked" if not set.
+// Returns the message ID.
+func (s *Session) Revoke(sub Subscription) (string, error) {
+	sub = s.subscription(sub, "authorization_revoked")
+	payload := map[string]any{"subscription": sub}
+
+	return s.Send(Metadata{
+		MessageType:         "revocation",
+		SubscriptionType:    sub.Type,
+		SubscriptionVersion: sub.Version,
+	}, payload)
+}
+
+// Keepalive sends a session_keepalive message.
+func (s *Session) Keepalive() error {
+	_, err := s.Send(Metadata{MessageType: "session_keepalive"}, struct{}{})
+
+	return err
+}
+
+// Reconnect sends a session_reconnect message with a reconnect URL served by the same server. The session is handed
+// over to the client connection to that URL, which receives a welcome message with the same session ID, and this
+// connection is closed.
+func (s *Session) Reconnect() error {
+	return s.ReconnectTo(s.server.wsURL(PathReconnect + s.id))
+}
+
+// ReconnectTo sends a session_reconnect message with the specified reconnect URL.
+func (s *Session) ReconnectTo(url string) error {
+	_, err := s.Send(Metadata{MessageType: "session_reconnect"}, map[string]any{
+		"session": sessionPayload{
+			ID:           s.id,
+			Status:       "reconnecting",
+			ConnectedAt:  s.connectedAt.UTC().Format(time.RFC3339Nano),
+			ReconnectURL: &url,
+		},
+	})
+
+	return err
+}
+
+// Send sends a message with the payload marshalled to JSON. The message ID and timestamp are generated if not set,
+// the generated timestamps are shifted by the offset set with ShiftTimestamps. Returns the message ID.
+func (s *Session) Send(m Metadata, payload any) (string, error) {
+	if m.MessageID == "" {
+		m.MessageID = newID()
+	}
+
+	if m.MessageTimestamp == "" {
+		s.mu.Lock()
+		offset := s.timestampOffset
+		s.mu.Unlock()
+
+		m.MessageTimestamp = time.Now().Add(offset).UTC().Format(time.RFC3339Nano)
+	}
+
+	data, err := json.Marshal(map[string]any{"metadata": m, "payload": payload})
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.SendRaw(data); err != nil {
+		return "", err
+	}
+
+	if m.MessageType == "notification" || m.MessageType == "revocation" {
+		s.keepForResend(m.MessageID, data)
+	}
+
+	return m.MessageID, nil
+}
+
+// keepForResend keeps the frame of the message for Resend, dropping the oldest one past resendLimit.
+func (s *Session) keepForResend(id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sent[id]; !ok {
+		s.sentOrder = append(s.sentOrder, id)
+	}
+
+	s.sent[id] = data
+
+	if len(s.sentOrder) > resendLimit {
+		delete(s.sent, s.sentOrder[0])
+		s.sentOrder = s.sentOrder[1:]
+	}
+}
+
+// SendRaw sends the data as a text message as is.
+func (s *Session) SendRaw(data []byte) error {
+	return s.write(websocket.MessageText, data)
+}
+
+// Close closes the connection with the specified status code, e.g. one of the Twitch close codes, and reason.
+func (s *Session) Close(code int, reason string) {
+	s.closeWith(websocket.StatusCode(code), reason)
+}
+
+// sendWelcome sends the session_welcome message.
+func (s *Session) sendWelcome() error {
+	keepalive := s.server.keepaliveSeconds
+
+	_, err := s.Send(Metadata{MessageType: "session_welcome"}, map[string]any{
+		"session": sessionPayload{
+			ID:                      s.id,
+			Status:                  "connected",
+			ConnectedAt:             s.connectedAt.UTC().Format(time.RFC3339Nano),
+			KeepaliveTimeoutSeconds: &keepalive,
+		},
+	})
+
+	return err
+}
+
+// subscription fills the empty fields of the subscription with the defaults bound to the session.
+func (s *Session) subscription(sub Subscription, status string) Subscription {
+	if sub.ID == "" {
+		sub.ID = newID()
+	}
+
+	if sub.Status == "" {
+		sub.Status = status
+	}
+
+	if sub.Version == "" {
+		sub.Version = "1"
+	}
+
+	if sub.Condition == nil {
+		sub.Condition = map[string]string{}
+	}
+
+	if sub.Transport.Method == "" {
+		sub.Transport = Transport{Method: "websocket", SessionID: s.id}
+	}
+
+	if sub.CreatedAt == "" {
+		sub.CreatedAt = s.connectedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return sub
+}
+
+// write sends a message after the configured delay and records the time it has been sent at.
+func (s *Session) write(typ websocket.MessageType, data []byte) error {
+	s.mu.Lock()
+	delay := s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-s.done:
+		case <-timer.C:
+		}
+	}
+
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := s.conn.Write(ctx, typ, data); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.lastSent = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// run reads the connection and sends keepalive messages until the connection is closed.
+func (s *Session) run(ctx context.Context) {
+	go s.read(ctx)
+
+	interval := s.server.keepaliveInterval
+
+	if interval <= 0 {
+		<-s.done
+		return
+	}
+
+	for {
+		s.mu.Lock()
+		next := s.lastSent.Add(interval)
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-timer.C:
+			s.mu.Lock()
+			due := !time.Now().Before(s.lastSent.Add(interval))
+
+			if due && s.keepalivePaused.Load() {
+				// a skipped keepalive restarts the interval like a sent one
+				s.lastSent, due = time.Now(), false
+			}
+			s.mu.Unlock()
+
+			if due {
+				_ = s.Keepalive()
+			}
+		case <-s.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// read consumes the client frames: like Twitch, the server closes the connection if the client sends a message.
+func (s *Session) read(ctx context.Context) {
+	for {
+		if _, _, err := s.conn.Read(ctx); err != nil {
+			s.finish()
+			return
+		}
+
+		go s.closeWith(CloseClientSentInboundTraffic, "client sent inbound traffic")
+	}
+}
+
+// closeWith closes the connection with the status code and reason unless it is already closed.
+func (s *Session) closeWith(code websocket.StatusCode, reason string) {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	_ = s.conn.Close(code, reason)
+	s.finish()
+}
+
+// finish marks the session closed.
+func (s *Session) finish() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,118 @@
+package twitchws
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectPolicy decides whether and when the client re-establishes a connection after a failed dial
+// or a dropped session.
+type ReconnectPolicy interface {
+	// NextDelay returns the delay before the given reconnect attempt and whether the attempt should be made at all.
+	// The attempt counter starts at 1 and is reset once a session welcome message is received again, elapsed is
+	// the time passed since the first attempt in the current series.
+	NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool)
+}
+
+// ExponentialBackoff is a ReconnectPolicy that grows the delay between attempts exponentially and never gives up.
+// Combine it with MaxAttempts or MaxElapsedTime to limit the number of attempts.
+type ExponentialBackoff struct {
+	// InitialInterval is the delay before the first reconnect attempt.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the delay between attempts. Zero means no cap.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor the delay grows by with every attempt. Values below 1 are treated as 1.
+	Multiplier float64
+
+	// Jitter is the randomization factor in range [0, 1] applied to every delay, e.g. 0.2 spreads the delay
+	// within ±20% of its nominal value.
+	Jitter float64
+}
+
+// Default values used by DefaultReconnectPolicy.
+const (
+	defaultBackoffInitialInterval = 500 * time.Millisecond
+	defaultBackoffMaxInterval     = 30 * time.Second
+	defaultBackoffMultiplier      = 2
+	defaultBackoffJitter          = 0.2
+)
+
+// DefaultReconnectPolicy returns an ExponentialBackoff policy with sensible defaults for the Twitch EventSub service:
+// delays start at 500ms, double with every attempt up to 30s and are randomized by ±20%.
+func DefaultReconnectPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval: defaultBackoffInitialInterval,
+		MaxInterval:     defaultBackoffMaxInterval,
+		Multiplier:      defaultBackoffMultiplier,
+		Jitter:          defaultBackoffJitter,
+	}
+}
+
+// NextDelay implements ReconnectPolicy.
+func (b *ExponentialBackoff) NextDelay(attempt int, _ time.Duration) (time.Duration, bool) {
+	multiplier := math.Max(b.Multiplier, 1)
+	delay := float64(b.InitialInterval) * math.Pow(multiplier, float64(max(attempt-1, 0)))
+
+	if b.MaxInterval > 0 {
+		delay = math.Min(delay, float64(b.MaxInterval))
+	}
+
+	// without a cap the delay saturates at the longest duration instead of overflowing to a negative one
+	delay = math.Min(delay, float64(math.MaxInt64))
+
+	if jitter := math.Min(math.Max(b.Jitter, 0), 1); jitter > 0 {
+		delay += delay * jitter * (2*rand.Float64() - 1) // nolint:gosec // jitter does not require a secure source
+	}
+
+	if delay >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64), true
+	}
+
+	return time.Duration(delay), true
+}
+
+// maxAttemptsPolicy limits the number of consecutive attempts allowed by the wrapped policy.
+type maxAttemptsPolicy struct {
+	attempts int
+	policy   ReconnectPolicy
+}
+
+// MaxAttempts wraps the policy to give up after the specified number of consecutive reconnect attempts.
+func MaxAttempts(attempts int, policy ReconnectPolicy) ReconnectPolicy {
+	return &maxAttemptsPolicy{attempts: attempts, policy: policy}
+}
+
+// NextDelay implements ReconnectPolicy.
+func (p *maxAttemptsPolicy) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if attempt > p.attempts {
+		return 0, false
+	}
+
+	return p.policy.NextDelay(attempt, elapsed)
+}
+
+// maxElapsedTimePolicy limits the total time the wrapped policy is allowed to keep reconnecting.
+type maxElapsedTimePolicy struct {
+	elapsed time.Duration
+	policy  ReconnectPolicy
+}
+
+// MaxElapsedTime wraps the policy to give up once the next attempt would start after the specified duration
+// since the first attempt in the current series.
+func MaxElapsedTime(elapsed time.Duration, policy ReconnectPolicy) ReconnectPolicy {
+	return &maxElapsedTimePolicy{elapsed: elapsed, policy: policy}
+}
+
+// NextDelay implements ReconnectPolicy.
+func (p *maxElapsedTimePolicy) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	delay, ok := p.policy.NextDelay(attempt, elapsed)
+
+	if !ok || elapsed+delay > p.elapsed {
+		return 0, false
+	}
+
+	return delay, true
+}
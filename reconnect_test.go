@@ -0,0 +1,93 @@
+package twitchws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := &ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+
+	for i, want := range expected {
+		delay, ok := policy.NextDelay(i+1, 0)
+
+		if !ok || delay != want {
+			t.Errorf("attempt %d: expected (%v, true), got (%v, %v)", i+1, want, delay, ok)
+		}
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	policy := &ExponentialBackoff{
+		InitialInterval: time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+	}
+
+	for range 100 {
+		delay, _ := policy.NextDelay(2, 0)
+
+		if delay < time.Second || delay > 3*time.Second {
+			t.Fatalf("delay %v is out of the jitter range", delay)
+		}
+	}
+}
+
+func TestExponentialBackoffUncapped(t *testing.T) {
+	policies := []*ExponentialBackoff{
+		{InitialInterval: 500 * time.Millisecond, Multiplier: 2},
+		{InitialInterval: 500 * time.Millisecond, Multiplier: 2, Jitter: 0.2},
+	}
+
+	for _, policy := range policies {
+		previous := time.Duration(0)
+
+		for _, attempt := range []int{1, 36, 41, 100, 5000} {
+			delay, ok := policy.NextDelay(attempt, 0)
+
+			if !ok || delay <= 0 {
+				t.Fatalf("attempt %d: expected positive delay, got (%v, %v)", attempt, delay, ok)
+			}
+
+			if policy.Jitter == 0 && delay < previous {
+				t.Errorf("attempt %d: delay %v is shorter than the previous %v", attempt, delay, previous)
+			}
+
+			previous = delay
+		}
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	policy := MaxAttempts(2, &ExponentialBackoff{InitialInterval: time.Millisecond})
+
+	for attempt, want := range []bool{true, true, false} {
+		if _, ok := policy.NextDelay(attempt+1, 0); ok != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt+1, want, ok)
+		}
+	}
+}
+
+func TestMaxElapsedTime(t *testing.T) {
+	policy := MaxElapsedTime(time.Second, &ExponentialBackoff{InitialInterval: 300 * time.Millisecond})
+
+	if _, ok := policy.NextDelay(1, 500*time.Millisecond); !ok {
+		t.Error("expected attempt within the elapsed time limit to be allowed")
+	}
+
+	if _, ok := policy.NextDelay(2, 800*time.Millisecond); ok {
+		t.Error("expected attempt beyond the elapsed time limit to be rejected")
+	}
+}
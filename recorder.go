@@ -0,0 +1,342 @@
+package twitchws
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/coder/websocket"
+)
+
+// Default values used for the zero fields of RecorderConfig.
+const (
+	defaultRecorderPrefix = "twitchws"
+	defaultRecorderBuffer = 1024
+)
+
+// RecordedFrame is a frame read from the connection along with the details of its receipt.
+type RecordedFrame struct {
+	// ReceivedAt is the local time the frame has been read at.
+	ReceivedAt time.Time `json:"received_at"`
+
+	// Generation is the sequence number of the connection the frame has been read from, incremented on every dial.
+	Generation uint64 `json:"generation"`
+
+	// SessionID is the ID of the session established when the frame has been read, empty before the welcome message.
+	SessionID string `json:"session_id,omitempty"`
+
+	// Binary is set for binary WebSocket frames.
+	Binary bool `json:"binary,omitempty"`
+
+	// Text is the frame as received if it is a valid UTF-8 text frame, stored as a JSON string, so archives keep
+	// it verbatim and readable.
+	Text string `json:"text,omitempty"`
+
+	// Raw is the frame as received if it is a binary frame or a text frame that is not valid UTF-8, encoded
+	// as base64 in JSON.
+	Raw []byte `json:"raw,omitempty"`
+}
+
+// Data returns the frame as received.
+func (f *RecordedFrame) Data() []byte {
+	if f.Raw != nil {
+		return f.Raw
+	}
+
+	return []byte(f.Text)
+}
+
+// FrameRecorder receives every frame read from the connection. RecordFrame is invoked from the message read loop,
+// so implementations must not block. The frame data must not be modified.
+type FrameRecorder interface {
+	RecordFrame(frame RecordedFrame)
+}
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Dir is the directory the archives are written to, it is created if it does not exist.
+	Dir string
+
+	// Prefix is the archive file name prefix, "twitchws" if not set.
+	Prefix string
+
+	// MaxSize is the uncompressed size in bytes after which a new archive is started, no limit if not set.
+	MaxSize int64
+
+	// MaxAge is the time after which a new archive is started, no limit if not set.
+	MaxAge time.Duration
+
+	// Gzip enables compression of the archives.
+	Gzip bool
+
+	// Buffer specifies the number of frames waiting to be written before new frames are dropped, 1024 if not set.
+	Buffer int
+}
+
+// Recorder is a FrameRecorder writing frames as JSON lines to rotating archives. Frames are written by a background
+// goroutine, so recording never blocks the message read loop: frames are dropped while the buffer is full.
+type Recorder struct {
+	config RecorderConfig
+	frames chan RecordedFrame
+	stop   chan struct{}
+	done   chan struct{}
+
+	// closeOnce guards the stop channel.
+	closeOnce sync.Once
+
+	// dropped counts the frames dropped because the buffer was full or the recorder was closed.
+	dropped atomic.Uint64
+
+	// closed indicates whether Close has been called.
+	closed atomic.Bool
+
+	// file is the current archive, nil until the first frame is written.
+	file *os.File
+
+	// gz compresses the current archive if enabled.
+	gz *gzip.Writer
+
+	// w buffers the writes to the current archive.
+	w *bufio.Writer
+
+	// size is the number of uncompressed bytes written to the current archive.
+	size int64
+
+	// openedAt is the time the current archive has been opened at.
+	openedAt time.Time
+
+	// line holds the frame being written, encoded by enc.
+	line bytes.Buffer
+
+	// enc encodes frames to line without escaping HTML characters, so text frames stay readable.
+	enc *json.Encoder
+
+	// err is the first write error, reported by Close.
+	err error
+}
+
+// NewRecorder creates a Recorder with the configuration defaults applied and starts its writer.
+func NewRecorder(config RecorderConfig) (*Recorder, error) {
+	const recorderDirPermissions = 0o750
+
+	if config.Prefix == "" {
+		config.Prefix = defaultRecorderPrefix
+	}
+
+	if config.Buffer <= 0 {
+		config.Buffer = defaultRecorderBuffer
+	}
+
+	if err := os.MkdirAll(config.Dir, recorderDirPermissions); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		config: config,
+		frames: make(chan RecordedFrame, config.Buffer),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	r.enc = json.NewEncoder(&r.line)
+	r.enc.SetEscapeHTML(false)
+
+	go r.run()
+
+	return r, nil
+}
+
+// RecordFrame implements FrameRecorder.
+func (r *Recorder) RecordFrame(frame RecordedFrame) {
+	if r.closed.Load() {
+		r.dropped.Add(1)
+		return
+	}
+
+	select {
+	case r.frames <- frame:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of frames dropped because the buffer was full or the recorder was closed.
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close writes the buffered frames, closes the current archive and returns the first error encountered while
+// writing. Frames recorded after Close are dropped.
+func (r *Recorder) Close() error {
+	r.closeOnce.Do(func() {
+		r.closed.Store(true)
+		close(r.stop)
+	})
+	<-r.done
+
+	return r.err
+}
+
+// run writes the frames until the recorder is closed, flushing the archive whenever the buffer is drained.
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	for {
+		select {
+		case frame := <-r.frames:
+			r.write(frame)
+
+			if len(r.frames) == 0 {
+				r.setErr(r.flush())
+			}
+		case <-r.stop:
+			for {
+				select {
+				case frame := <-r.frames:
+					r.write(frame)
+				default:
+					r.setErr(r.closeArchive())
+					return
+				}
+			}
+		}
+	}
+}
+
+// write appends the frame to the current archive, rotating it if due.
+func (r *Recorder) write(frame RecordedFrame) {
+	r.line.Reset()
+
+	if err := r.enc.Encode(&frame); err != nil {
+		r.setErr(err)
+		return
+	}
+
+	if err := r.rotateIfDue(int64(r.line.Len())); err != nil {
+		r.setErr(err)
+		return
+	}
+
+	n, err := r.w.Write(r.line.Bytes())
+	r.size += int64(n)
+	r.setErr(err)
+}
+
+// rotateIfDue starts a new archive if there is none yet or the current one exceeds the configured limits.
+func (r *Recorder) rotateIfDue(next int64) error {
+	if r.file != nil {
+		sizeExceeded := r.config.MaxSize > 0 && r.size > 0 && r.size+next > r.config.MaxSize
+		ageExceeded := r.config.MaxAge > 0 && time.Since(r.openedAt) >= r.config.MaxAge
+
+		if !sizeExceeded && !ageExceeded {
+			return nil
+		}
+
+		if err := r.closeArchive(); err != nil {
+			return err
+		}
+	}
+
+	return r.openArchive()
+}
+
+// openArchive creates a new archive named after the prefix and the current time.
+func (r *Recorder) openArchive() error {
+	const archivePermissions = 0o600
+	r.openedAt = time.Now()
+	name := fmt.Sprintf("%s-%s.jsonl", r.config.Prefix, r.openedAt.UTC().Format("20060102T150405.000000000Z"))
+
+	if r.config.Gzip {
+		name += ".gz"
+	}
+
+	file, err := os.OpenFile(filepath.Join(r.config.Dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, archivePermissions)
+
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = file
+	r.file = file
+	r.size = 0
+
+	if r.config.Gzip {
+		r.gz = gzip.NewWriter(file)
+		w = r.gz
+	}
+
+	r.w = bufio.NewWriter(w)
+
+	return nil
+}
+
+// flush writes the buffered data of the current archive to the file.
+func (r *Recorder) flush() error {
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.w.Flush()
+
+	if r.gz != nil && err == nil {
+		err = r.gz.Flush()
+	}
+
+	return err
+}
+
+// closeArchive flushes and closes the current archive, if any.
+func (r *Recorder) closeArchive() error {
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.w.Flush()
+
+	if r.gz != nil {
+		err = errors.Join(err, r.gz.Close())
+	}
+
+	err = errors.Join(err, r.file.Close())
+	r.file, r.gz, r.w = nil, nil, nil
+
+	return err
+}
+
+// setErr keeps the first write error.
+func (r *Recorder) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// recordFrame passes the frame read from the connection with the specified generation to the recorder, if configured.
+func (c *Client) recordFrame(generation uint64, msgType websocket.MessageType, data []byte) {
+	if c.recorder == nil {
+		return
+	}
+
+	frame := RecordedFrame{
+		ReceivedAt: time.Now(),
+		Generation: generation,
+		SessionID:  c.currentSessionID(),
+		Binary:     msgType == websocket.MessageBinary,
+	}
+
+	if !frame.Binary && utf8.Valid(data) {
+		frame.Text = string(data)
+	} else {
+		frame.Raw = data
+	}
+
+	c.recorder.RecordFrame(frame)
+}
@@ -0,0 +1,195 @@
+package twitchws
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// readRecordedFrames reads the frames from the archives in the directory in their rotation order.
+func readRecordedFrames(t *testing.T, dir string) [][]RecordedFrame {
+	t.Helper()
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.jsonl*"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archives := make([][]RecordedFrame, 0, len(names))
+
+	for _, name := range names {
+		f, err := os.Open(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r io.Reader = f
+
+		if filepath.Ext(name) == ".gz" {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var frames []RecordedFrame
+
+		for s := bufio.NewScanner(r); s.Scan(); {
+			var frame RecordedFrame
+
+			if err = json.Unmarshal(s.Bytes(), &frame); err != nil {
+				t.Fatal(err)
+			}
+
+			frames = append(frames, frame)
+		}
+
+		_ = f.Close()
+		archives = append(archives, frames)
+	}
+
+	return archives
+}
+
+func TestRecorder(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		r, err := NewRecorder(RecorderConfig{Dir: dir, Gzip: compress})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the notification is indented, so it is recorded verbatim only if the whitespace is kept
+		notification := chatMessageNotification(0)
+		c := NewClient(websocketTwitch, WithRecorder(r))
+		c.recordFrame(1, websocket.MessageText, notification)
+		c.recordFrame(1, websocket.MessageText, []byte("{<not json>"))
+		c.recordFrame(2, websocket.MessageBinary, []byte{0x00, 0x01})
+		c.recordFrame(2, websocket.MessageText, []byte{0xff})
+
+		if err = r.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		archives := readRecordedFrames(t, dir)
+
+		if len(archives) != 1 || len(archives[0]) != 4 {
+			t.Fatalf("gzip %v: expected single archive with 4 frames, got %v", compress, archives)
+		}
+
+		frames := archives[0]
+
+		if !bytes.Equal(frames[0].Data(), notification) || frames[0].Generation != 1 ||
+			frames[0].ReceivedAt.IsZero() {
+			t.Errorf("gzip %v: unexpected JSON frame: %+v", compress, frames[0])
+		}
+
+		if string(frames[1].Data()) != "{<not json>" || frames[1].Raw != nil {
+			t.Errorf("gzip %v: unexpected invalid JSON frame: %+v", compress, frames[1])
+		}
+
+		if !frames[2].Binary || frames[2].Generation != 2 || !bytes.Equal(frames[2].Data(), []byte{0x00, 0x01}) {
+			t.Errorf("gzip %v: unexpected binary frame: %+v", compress, frames[2])
+		}
+
+		if frames[3].Binary || !bytes.Equal(frames[3].Data(), []byte{0xff}) {
+			t.Errorf("gzip %v: unexpected invalid UTF-8 frame: %+v", compress, frames[3])
+		}
+	}
+}
+
+func TestRecorderRotation(t *testing.T) {
+	dir := t.TempDir()
+	frame := chatMessageNotification(0)
+	// every archive holds at least a single frame, whatever its size
+	r, err := NewRecorder(RecorderConfig{Dir: dir, MaxSize: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		r.RecordFrame(RecordedFrame{ReceivedAt: time.Now(), Text: string(frame)})
+		// keep archive names unique on platforms with a coarse clock
+		time.Sleep(time.Millisecond)
+	}
+
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives := readRecordedFrames(t, dir)
+
+	if len(archives) != 3 {
+		t.Fatalf("expected 3 archives, got %d", len(archives))
+	}
+
+	r.RecordFrame(RecordedFrame{Text: string(frame)})
+
+	if r.Dropped() != 1 {
+		t.Errorf("expected frame recorded after Close to be dropped, got %d dropped", r.Dropped())
+	}
+}
+
+type frameCollector struct {
+	mu     sync.Mutex
+	frames []RecordedFrame
+}
+
+func (fc *frameCollector) RecordFrame(frame RecordedFrame) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.frames = append(fc.frames, frame)
+}
+
+func TestWithRecorder(t *testing.T) {
+	url := newTestServer(t, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fc := &frameCollector{}
+	c := NewClient(url, WithLogger(discardLogger()), WithRecorder(fc), WithOnNotification(func(*Metadata, *Payload) {
+		cancel()
+	}))
+	_ = c.Run(ctx)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if len(fc.frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(fc.frames))
+	}
+
+	var welcome struct {
+		Payload struct {
+			Session Session `json:"session"`
+		} `json:"payload"`
+	}
+
+	if err := json.Unmarshal(fc.frames[0].Data(), &welcome); err != nil {
+		t.Fatal(err)
+	}
+
+	if fc.frames[0].SessionID != "" || fc.frames[1].SessionID != welcome.Payload.Session.ID {
+		t.Errorf("unexpected session IDs: %q, %q", fc.frames[0].SessionID, fc.frames[1].SessionID)
+	}
+
+	for _, frame := range fc.frames {
+		if frame.Generation != 1 {
+			t.Errorf("expected frame of the first connection, got generation %d", frame.Generation)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package twitchws
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// ErrEventTypeConflict indicates an attempt to register a different event struct for a subscription type and version
+// that already has one.
+var ErrEventTypeConflict = errors.New("event type already registered")
+
+// DefaultRegistry holds the event structs of the subscription types supported out of the box. It is used by every
+// client, after the registry set with WithRegistry.
+var DefaultRegistry = newRegistry(eventSubTypes)
+
+// Registry maps subscription types and versions to the event structs notifications are decoded into.
+// It is safe for concurrent use.
+type Registry struct {
+	// mu guards types.
+	mu sync.RWMutex
+
+	// types maps subscription types to their supported versions.
+	types map[string][]EventSubScope
+}
+
+// NewRegistry creates an empty registry, e.g. to override event structs for a single client with WithRegistry.
+func NewRegistry() *Registry {
+	return newRegistry(nil)
+}
+
+// newRegistry creates a registry with a copy of the specified subscription types.
+func newRegistry(types map[string][]EventSubScope) *Registry {
+	r := &Registry{types: make(map[string][]EventSubScope, len(types))}
+
+	for subType, scopes := range types {
+		r.types[subType] = slices.Clone(scopes)
+	}
+
+	return r
+}
+
+// Register maps the subscription type and version to the factory creating an event struct to decode notifications
+// into, e.g. func() any { return new(MyEvent) }. Returns an error wrapping ErrEventTypeConflict if the version
+// already has a factory creating a different type; registering the same type again is a no-op.
+func (r *Registry) Register(subType, version string, factory func() any) error {
+	if factory == nil {
+		return fmt.Errorf("register %s v%s: nil factory", subType, version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the scopes are copied on write, so the snapshots taken by readers are never modified
+	scopes := slices.Clone(r.types[subType])
+	i := slices.IndexFunc(scopes, func(scope EventSubScope) bool {
+		return scope.Version == version
+	})
+
+	switch {
+	case i < 0:
+		r.types[subType] = append(scopes, EventSubScope{Version: version, New: factory})
+	case scopes[i].New == nil:
+		scopes[i].New = factory
+		r.types[subType] = scopes
+	default:
+		registered, requested := reflect.TypeOf(scopes[i].New()), reflect.TypeOf(factory())
+
+		if registered != requested {
+			return fmt.Errorf("%w: %s v%s is decoded into %v, not %v", ErrEventTypeConflict, subType, version,
+				registered, requested)
+		}
+	}
+
+	return nil
+}
+
+// RegisterEventType registers the event struct factory for the subscription type and version in DefaultRegistry,
+// see Registry.Register.
+func RegisterEventType(subType, version string, factory func() any) error {
+	return DefaultRegistry.Register(subType, version, factory)
+}
+
+// lookup returns the scope of the subscription type and version. Returns ErrUnsupportedType if the subscription
+// type is unknown or ErrUnsupportedVersion if the version has no event struct.
+func (r *Registry) lookup(subType, version string) (*EventSubScope, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if scopes, ok := r.types[subType]; ok {
+		return getMatchingEventSub(scopes, version)
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// snapshot returns a copy of the registered subscription types.
+func (r *Registry) snapshot() map[string][]EventSubScope {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return maps.Clone(r.types)
+}
+
+// registryChain is a list of registries looked up in order.
+type registryChain []*Registry
+
+// lookup returns the scope of the subscription type and version from the first registry that supports it.
+// Returns ErrUnsupportedVersion if any registry knows the subscription type, ErrUnsupportedType otherwise.
+func (rc registryChain) lookup(subType, version string) (*EventSubScope, error) {
+	err := ErrUnsupportedType
+
+	for _, r := range rc {
+		scope, lookupErr := r.lookup(subType, version)
+
+		if lookupErr == nil {
+			return scope, nil
+		}
+
+		if errors.Is(lookupErr, ErrUnsupportedVersion) {
+			err = lookupErr
+		}
+	}
+
+	return nil, err
+}
+
+// registries returns the registries the client looks event structs up in.
+func (c *Client) registries() registryChain {
+	if c.registry == nil {
+		return registryChain{DefaultRegistry}
+	}
+
+	return registryChain{c.registry, DefaultRegistry}
+}
@@ -0,0 +1,108 @@
+package twitchws
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/eventsub"
+)
+
+type customChatMessage struct {
+	BroadcasterUserID string `json:"broadcaster_user_id"`
+	Message           struct {
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := newRegistry(map[string][]EventSubScope{
+		"automod.message.hold": {{Version: "1"}},
+		"channel.follow":       {{Version: "2", New: newEvent[eventsub.ChannelFollowEvent]}},
+	})
+
+	if err := r.Register("channel.follow", "2", newEvent[eventsub.ChannelFollowEvent]); err != nil {
+		t.Errorf("expected registering the same type again to succeed: %v", err)
+	}
+
+	if err := r.Register("channel.follow", "2", newEvent[customChatMessage]); !errors.Is(err, ErrEventTypeConflict) {
+		t.Errorf("expected %v, got %v", ErrEventTypeConflict, err)
+	}
+
+	if err := r.Register("channel.follow", "3", newEvent[customChatMessage]); err != nil {
+		t.Errorf("expected new version to be registered: %v", err)
+	}
+
+	if err := r.Register("automod.message.hold", "1", newEvent[customChatMessage]); err != nil {
+		t.Errorf("expected version without event struct to be registered: %v", err)
+	}
+
+	if err := r.Register("channel.follow", "4", nil); err == nil {
+		t.Error("expected nil factory to be rejected")
+	}
+
+	for _, tt := range []struct{ subType, version string }{
+		{"channel.follow", "2"},
+		{"channel.follow", "3"},
+		{"automod.message.hold", "1"},
+	} {
+		if _, err := r.lookup(tt.subType, tt.version); err != nil {
+			t.Errorf("%s v%s: unexpected lookup error: %v", tt.subType, tt.version, err)
+		}
+	}
+}
+
+func TestRegistryChainLookup(t *testing.T) {
+	override := NewRegistry()
+
+	if err := override.Register("channel.chat.message", "1", newEvent[customChatMessage]); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := registryChain{override, DefaultRegistry}
+	scope, err := rc.lookup("channel.chat.message", "1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := scope.New().(*customChatMessage); !ok {
+		t.Errorf("expected client registry to take precedence, got %T", scope.New())
+	}
+
+	if _, err = rc.lookup("channel.chat.message", "42"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedVersion, err)
+	}
+
+	if _, err = rc.lookup("channel.unknown", "1"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedType, err)
+	}
+}
+
+func TestWithRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.Register("channel.custom", "1", newEvent[customChatMessage]); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(websocketTwitch, WithRegistry(r))
+	data := bytes.ReplaceAll(chatMessageNotification(3), []byte("channel.chat.message"), []byte("channel.custom"))
+	n, err := unmarshalTestNotification(data, c.registries())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event, ok := n.Event.(*customChatMessage); !ok || event.Message.Text != "message 3" {
+		t.Errorf("unexpected event: %#v", n.Event)
+	}
+
+	if _, err = unmarshalTestNotification(data, NewClient(websocketTwitch).registries()); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected other clients not to be affected, got %v", err)
+	}
+
+	if types := subscriptionTypesOf[customChatMessage](c.registries()); len(types) != 1 || types[0] != "channel.custom" {
+		t.Errorf("unexpected subscription types: %v", types)
+	}
+}
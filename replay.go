@@ -0,0 +1,281 @@
+package twitchws
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// ReplayConfig configures a Replayer.
+type ReplayConfig struct {
+	// Speed scales the pace of the replay relative to the recorded receipt times: 1 replays in real time, 10 ten
+	// times faster. Frames are replayed as fast as possible if not set.
+	Speed float64
+
+	// SubscriptionTypes limits the replayed notifications and revocations to the specified subscription types.
+	// Session messages are always replayed as they maintain the session state.
+	SubscriptionTypes []string
+
+	// From skips the frames received before the specified time if set.
+	From time.Time
+
+	// To skips the frames received at or after the specified time if set.
+	To time.Time
+}
+
+// Replayer feeds frames recorded by a Recorder through the message handling of a client, invoking the same
+// callbacks, typed handlers and middlewares as the live connection. The client never dials, session_reconnect
+// messages only update the session. The recorded frames of the connection the session is handed over to are
+// handled like the live reconnect does: up to its welcome message, which only updates the session, without callbacks.
+type Replayer struct {
+	config ReplayConfig
+	client *Client
+
+	// generation is the recorded generation of the connection the frames are handled from like the live ones.
+	generation uint64
+
+	// handover is the recorded generation of the connection the session is handed over to after a
+	// session_reconnect message, zero until its first frame is replayed.
+	handover uint64
+
+	// awaitingHandover is set by a replayed session_reconnect message until the session has been handed over.
+	awaitingHandover bool
+}
+
+// NewReplayer creates a Replayer with a client configured with the specified options.
+func NewReplayer(config ReplayConfig, opts ...Option) *Replayer {
+	c := newClient("", opts...)
+	c.replaying = true
+
+	return &Replayer{config: config, client: c}
+}
+
+// Client returns the client the frames are replayed through, e.g. to register typed handlers with On.
+func (r *Replayer) Client() *Client {
+	return r.client
+}
+
+// ReplayFile replays the archives in the specified order as a single recording. Archives with the .gz extension
+// are decompressed. Errors are reported the same way as by Replay.
+func (r *Replayer) ReplayFile(ctx context.Context, names ...string) error {
+	return r.replay(ctx, func() error {
+		for _, name := range names {
+			if err := r.replayFile(ctx, name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// replayFile replays the frames of a single archive.
+func (r *Replayer) replayFile(ctx context.Context, name string) error {
+	f, err := os.Open(filepath.Clean(name))
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var src io.Reader = f
+
+	if filepath.Ext(name) == ".gz" {
+		gz, err := gzip.NewReader(f)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = gz.Close()
+		}()
+
+		src = gz
+	}
+
+	return r.replayFrames(ctx, src)
+}
+
+// Replay replays the frames read from src until its end or until ctx is done. Errors of the individual messages
+// are reported to the OnError callback and do not stop the replay. Returns an error if the recording cannot be read,
+// ctx.Err() if the replay has been cancelled or ErrAlreadyInUse if the client is already replaying.
+func (r *Replayer) Replay(ctx context.Context, src io.Reader) error {
+	return r.replay(ctx, func() error {
+		return r.replayFrames(ctx, src)
+	})
+}
+
+// replay prepares the client for a replay, runs it and restores the client afterward. Every replay tracks
+// redelivered messages in a new in-process store instead of the one set with WithDedupStore, so the same
+// recording can be replayed again and the live deduplication state is never affected.
+func (r *Replayer) replay(ctx context.Context, run func() error) error {
+	c := r.client
+
+	if !c.setActive() {
+		return ErrAlreadyInUse
+	}
+
+	defer c.setInactive()
+
+	r.generation, r.handover, r.awaitingHandover = 0, 0, false
+	c.msgTracking = NewMemoryDedupStore()
+	c.openEventStream()
+	c.initMainContext(ctx)
+	defer c.ctxCancel()
+
+	if c.dispatcherConfig != nil {
+		c.dispatcher = newDispatcher(*c.dispatcherConfig, c.metrics)
+	}
+
+	defer func() {
+		if c.dispatcher != nil {
+			c.dispatcher.stop()
+			c.dispatcher = nil
+		}
+
+		c.closeEventStream()
+		c.session.Store(nil)
+	}()
+
+	return run()
+}
+
+// replayFrames replays the frames read from src through the client prepared by replay.
+func (r *Replayer) replayFrames(ctx context.Context, src io.Reader) error {
+	c := r.client
+	dec := json.NewDecoder(src)
+	var previous time.Time
+
+	for {
+		var frame RecordedFrame
+
+		if err := dec.Decode(&frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		m := frameMetadata(&frame)
+
+		if !r.matches(&frame, m.SubscriptionType) {
+			continue
+		}
+
+		if err := r.wait(ctx, previous, frame.ReceivedAt); err != nil {
+			return err
+		}
+
+		previous = frame.ReceivedAt
+		msgType := websocket.MessageText
+
+		if frame.Binary {
+			msgType = websocket.MessageBinary
+		}
+
+		if r.generation == 0 {
+			r.generation = frame.Generation
+		}
+
+		if frame.Generation != r.generation {
+			if r.awaitingHandover && (r.handover == 0 || r.handover == frame.Generation) {
+				r.handOver(&frame, msgType)
+				continue
+			}
+
+			// a new connection after a disconnect or a failed handover is handled like the live one
+			r.generation, r.handover, r.awaitingHandover = frame.Generation, 0, false
+		}
+
+		// message errors are reported to the OnError callback, the replay goes on like the live client would
+		_ = c.handleMessage(ctx, msgType, frame.Data())
+
+		if m.MessageType == "session_reconnect" {
+			r.awaitingHandover = true
+		}
+	}
+}
+
+// matches reports whether the frame with the subscription type passes the time range and subscription type filters.
+func (r *Replayer) matches(frame *RecordedFrame, subType string) bool {
+	if !r.config.From.IsZero() && frame.ReceivedAt.Before(r.config.From) {
+		return false
+	}
+
+	if !r.config.To.IsZero() && !frame.ReceivedAt.Before(r.config.To) {
+		return false
+	}
+
+	if len(r.config.SubscriptionTypes) == 0 || subType == "" {
+		return true
+	}
+
+	return slices.Contains(r.config.SubscriptionTypes, subType)
+}
+
+// handOver handles a frame of the connection the session is handed over to the way reconnectWaitWelcome does:
+// the welcome message only updates the session and completes the handover, the frames before it are skipped.
+func (r *Replayer) handOver(frame *RecordedFrame, msgType websocket.MessageType) {
+	r.handover = frame.Generation
+	env, err := decodeEnvelope(msgType, frame.Data())
+
+	if err != nil || env.Metadata.MessageType != "session_welcome" {
+		return
+	}
+
+	if _, _, err = welcomeMessageHandler(r.client, &env.Metadata, env.Payload); err != nil {
+		return
+	}
+
+	r.generation, r.handover, r.awaitingHandover = frame.Generation, 0, false
+}
+
+// recordedMetadata holds the metadata fields the replay filters the frames and tracks the handover by.
+type recordedMetadata struct {
+	MessageType      string `json:"message_type"`
+	SubscriptionType string `json:"subscription_type"`
+}
+
+// frameMetadata returns the metadata of the frame, empty if the frame is not a valid message.
+func frameMetadata(frame *RecordedFrame) recordedMetadata {
+	var msg struct {
+		Metadata recordedMetadata `json:"metadata"`
+	}
+
+	if !frame.Binary {
+		_ = json.Unmarshal(frame.Data(), &msg)
+	}
+
+	return msg.Metadata
+}
+
+// wait sleeps for the time passed between the receipt of the previous and the current frame scaled by the replay
+// speed. Returns ctx.Err() if ctx is done before.
+func (r *Replayer) wait(ctx context.Context, previous, current time.Time) error {
+	if r.config.Speed <= 0 || previous.IsZero() || !current.After(previous) {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Duration(float64(current.Sub(previous)) / r.config.Speed))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
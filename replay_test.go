@@ -0,0 +1,306 @@
+package twitchws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/vpetrigo/go-twitch-ws/pkg/twitchwstest"
+)
+
+// recording returns the JSON lines of the frames received a second apart starting at the specified time.
+func recording(t *testing.T, start time.Time, frames ...[]byte) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	for i, frame := range frames {
+		err := enc.Encode(RecordedFrame{
+			ReceivedAt: start.Add(time.Duration(i) * time.Second),
+			Generation: 1,
+			Text:       string(frame),
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &buf
+}
+
+func replayFrames() [][]byte {
+	reconnect := []byte(`{
+		"metadata": {
+			"message_id": "84c1e79a-2a4b-4c13-ba0b-4312293e9308",
+			"message_type": "session_reconnect",
+			"message_timestamp": "2022-11-18T09:10:11.634234626Z"
+		},
+		"payload": {
+			"session": {
+				"id": "AQoQexAWVYKSTIu4ec_2VAxyuhAB",
+				"status": "reconnecting",
+				"keepalive_timeout_seconds": null,
+				"reconnect_url": "wss://eventsub.wss.twitch.tv?...",
+				"connected_at": "2022-11-16T10:11:12.634234626Z"
+			}
+		}
+	}`)
+
+	return [][]byte{
+		welcomeMessage(),
+		chatMessageNotification(0),
+		bytes.ReplaceAll(chatMessageNotification(1), []byte("channel.chat.message"), []byte("channel.unknown")),
+		reconnect,
+		chatMessageNotification(2),
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		config        ReplayConfig
+		notifications int
+		reported      int
+	}{
+		{"all", ReplayConfig{}, 2, 1},
+		{"subscription types", ReplayConfig{SubscriptionTypes: []string{"channel.chat.message"}}, 2, 0},
+		{"time range", ReplayConfig{From: start.Add(2 * time.Second), To: start.Add(4 * time.Second)}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notifications, reported int
+
+			r := NewReplayer(tt.config,
+				WithLogger(discardLogger()),
+				WithOnNotification(func(*Metadata, *Payload) {
+					notifications++
+				}),
+				WithOnError(func(ErrorEvent) {
+					reported++
+				}))
+
+			if err := r.Replay(context.Background(), recording(t, start, replayFrames()...)); err != nil {
+				t.Fatal(err)
+			}
+
+			if notifications != tt.notifications || reported != tt.reported {
+				t.Errorf("expected %d notifications and %d errors, got %d and %d",
+					tt.notifications, tt.reported, notifications, reported)
+			}
+		})
+	}
+}
+
+func TestReplayerReconnect(t *testing.T) {
+	var (
+		r        *Replayer
+		sessions []string
+	)
+
+	r = NewReplayer(ReplayConfig{}, WithLogger(discardLogger()), WithOnNotification(func(*Metadata, *Payload) {
+		s, _ := r.Client().Session()
+		sessions = append(sessions, s.Status)
+	}))
+
+	if err := r.Replay(context.Background(), recording(t, time.Now(), replayFrames()...)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 2 || sessions[0] != "connected" || sessions[1] != "reconnecting" {
+		t.Errorf("unexpected session states: %v", sessions)
+	}
+
+	if r.Client().isReconnectRequired.Load() {
+		t.Error("expected replayed session_reconnect not to trigger a reconnect")
+	}
+}
+
+func TestReplayerRecordedReconnect(t *testing.T) {
+	srv := twitchwstest.NewServer()
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fc := &frameCollector{}
+	notified := make(chan struct{}, 2)
+	c := NewClient(srv.URL(), WithLogger(discardLogger()), WithRecorder(fc),
+		WithOnNotification(func(*Metadata, *Payload) {
+			notified <- struct{}{}
+		}))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_ = c.Run(ctx)
+	}()
+
+	first, err := srv.WaitSession(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := twitchwstest.Subscription{Type: "channel.chat.message"}
+
+	if _, err = first.Notify(sub, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = first.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the previous connection is closed once the welcome message of the new one has been sent
+	select {
+	case <-first.Done():
+	case <-ctx.Done():
+		t.Fatal("session has not been handed over to the reconnect URL")
+	}
+
+	second, _ := srv.Session(first.ID())
+
+	if _, err = second.Notify(sub, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		select {
+		case <-notified:
+		case <-ctx.Done():
+			t.Fatal("client has not been notified")
+		}
+	}
+
+	cancel()
+	<-done
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	fc.mu.Lock()
+	for _, frame := range fc.frames {
+		if err = enc.Encode(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fc.mu.Unlock()
+
+	var welcomes, notifications int
+
+	r := NewReplayer(ReplayConfig{}, WithLogger(discardLogger()),
+		WithOnWelcome(func(*Metadata, *Payload) {
+			welcomes++
+		}),
+		WithOnNotification(func(*Metadata, *Payload) {
+			notifications++
+		}))
+
+	if err = r.Replay(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the welcome message of the connection the session is handed over to only updates the session
+	if welcomes != 1 || notifications != 2 {
+		t.Errorf("expected 1 welcome and 2 notifications, got %d and %d", welcomes, notifications)
+	}
+}
+
+func TestReplayerDedup(t *testing.T) {
+	var notifications int
+
+	store := NewMemoryDedupStore()
+	r := NewReplayer(ReplayConfig{}, WithLogger(discardLogger()), WithDedupStore(store),
+		WithOnNotification(func(*Metadata, *Payload) {
+			notifications++
+		}))
+
+	// the redelivered notification is suppressed within a replay, but the recording can be replayed again
+	for range 2 {
+		err := r.Replay(context.Background(), recording(t, time.Now(), chatMessageNotification(0), chatMessageNotification(0)))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if notifications != 2 {
+		t.Errorf("expected a notification per replay, got %d", notifications)
+	}
+
+	if store.SeenOrMark("notification-0", time.Minute) {
+		t.Error("expected the replay not to affect the configured dedup store")
+	}
+}
+
+func TestReplayerSpeed(t *testing.T) {
+	r := NewReplayer(ReplayConfig{Speed: 20}, WithLogger(discardLogger()))
+	begin := time.Now()
+
+	// 4 seconds between the first and the last frame replayed 20 times faster
+	if err := r.Replay(context.Background(), recording(t, begin, replayFrames()...)); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(begin); elapsed < 200*time.Millisecond {
+		t.Errorf("expected replay to be paced, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := r.Replay(ctx, recording(t, begin, replayFrames()...)); err == nil {
+		t.Error("expected cancelled replay to fail")
+	}
+}
+
+func TestReplayerFile(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{Dir: dir, Gzip: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient(websocketTwitch, WithRecorder(rec))
+
+	for _, frame := range replayFrames() {
+		c.recordFrame(1, websocket.MessageText, frame)
+	}
+
+	if err = rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.jsonl.gz"))
+
+	if err != nil || len(names) != 1 {
+		t.Fatalf("expected a single archive, got %v (%v)", names, err)
+	}
+
+	var notifications int
+
+	r := NewReplayer(ReplayConfig{}, WithLogger(discardLogger()), WithOnNotification(func(*Metadata, *Payload) {
+		notifications++
+	}))
+
+	if err = r.ReplayFile(context.Background(), names...); err != nil {
+		t.Fatal(err)
+	}
+
+	if notifications != 2 {
+		t.Errorf("expected 2 notifications, got %d", notifications)
+	}
+}
@@ -0,0 +1,66 @@
+package twitchws
+
+import "fmt"
+
+// ConnectionState represents the lifecycle state of the client connection.
+type ConnectionState int32
+
+const (
+	// StateInactive represents the client state where it is not active or engaged in any connection-related process.
+	StateInactive ConnectionState = iota
+
+	// StateConnecting represents the client state during the process of establishing a connection.
+	StateConnecting
+
+	// StateConnected indicates that the client has successfully established a connection and is in a stable connected state.
+	StateConnected
+
+	// StateReconnecting indicates that the client hands the session over to a new connection on the server request.
+	StateReconnecting
+
+	// StateDisconnected represents the state where the client has been disconnected and requires cleanup or reconnection setup.
+	StateDisconnected
+)
+
+// connectionStateNames maps connection states to their human-readable names.
+var connectionStateNames = map[ConnectionState]string{
+	StateInactive:     "inactive",
+	StateConnecting:   "connecting",
+	StateConnected:    "connected",
+	StateReconnecting: "reconnecting",
+	StateDisconnected: "disconnected",
+}
+
+// String returns the human-readable name of the connection state.
+func (s ConnectionState) String() string {
+	if name, ok := connectionStateNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("state(%d)", int32(s))
+}
+
+// OnStateChangeFn defines a callback function to be executed on a connection state transition, receiving the previous
+// and the current state along with the error that caused the transition, if any.
+type OnStateChangeFn func(previous, current ConnectionState, err error)
+
+// State returns the current connection state of the client. It is safe to call concurrently with the client operation.
+func (c *Client) State() ConnectionState {
+	return ConnectionState(c.state.Load())
+}
+
+// setState transitions the client to the specified state and notifies the onStateChange callback
+// and the Lifecycle channel.
+func (c *Client) setState(state ConnectionState, err error) {
+	previous := ConnectionState(c.state.Swap(int32(state)))
+
+	if previous == state {
+		return
+	}
+
+	if c.onStateChange != nil {
+		c.onStateChange(previous, state, err)
+	}
+
+	c.publishLifecycle(previous, state, err)
+}
@@ -0,0 +1,57 @@
+package twitchws
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConnectionStateString(t *testing.T) {
+	if s := StateReconnecting.String(); s != "reconnecting" {
+		t.Errorf("unexpected state name: %s", s)
+	}
+
+	if s := ConnectionState(42).String(); s != "state(42)" {
+		t.Errorf("unexpected unknown state name: %s", s)
+	}
+}
+
+func TestClientSetState(t *testing.T) {
+	type transition struct {
+		previous, current ConnectionState
+		err               error
+	}
+
+	var transitions []transition
+
+	c := NewClient(websocketTwitch, WithOnStateChange(func(previous, current ConnectionState, err error) {
+		transitions = append(transitions, transition{previous, current, err})
+	}))
+	errLost := errors.New("lost")
+
+	if c.State() != StateInactive {
+		t.Fatalf("expected new client to be inactive, got %v", c.State())
+	}
+
+	c.setState(StateConnecting, nil)
+	c.setState(StateConnecting, nil)
+	c.setState(StateDisconnected, errLost)
+
+	expected := []transition{
+		{StateInactive, StateConnecting, nil},
+		{StateConnecting, StateDisconnected, errLost},
+	}
+
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected %d transitions, got %v", len(expected), transitions)
+	}
+
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("transition %d: expected %v, got %v", i, expected[i], transitions[i])
+		}
+	}
+
+	if c.State() != StateDisconnected {
+		t.Errorf("expected disconnected state, got %v", c.State())
+	}
+}